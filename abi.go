@@ -1,16 +1,22 @@
 package msgpack
 
 import (
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strings"
+	"context"
 	"encoding/json"
-	"github.com/bitly/go-simplejson"
+	"fmt"
+)
+
+//ParseOption changes how ParseAbi parses an abi
+type ParseOption int
+
+const (
+	//ValidateAbi makes ParseAbi validate the abi, as ParseAbiStrict does
+	ValidateAbi ParseOption = iota + 1
 )
 
-//ParseAbi parse abiraw to struct for contracts
-func ParseAbi(abiRaw []byte) (*ABI, error) {
+//ParseAbi parse abiraw to struct for contracts. The abi is only validated
+//when the ValidateAbi option is given.
+func ParseAbi(abiRaw []byte, opts ...ParseOption) (*ABI, error) {
         abis := &ABIStructs{}
         err := json.Unmarshal(abiRaw, abis)
         if err != nil {
@@ -27,42 +33,129 @@ func ParseAbi(abiRaw []byte) (*ABI, error) {
                 return &ABI{}, err
         }
 
+        for _, opt := range opts {
+                if opt == ValidateAbi {
+                        return abi, validateParsedAbi(abi, abiRaw)
+                }
+        }
         return abi, nil
 }
 
-//GetAbibyContractName function
-func GetAbibyContractName(contractname string) (ABI, error) {
-	var abistring string
-	NodeIp := "127.0.0.1"
-	addr := "http://" + NodeIp + ":8080/rpc"
-	params := `service=bottos&method=Chain.GetAbi&request={
-			"contract":"%s"}`
-	s := fmt.Sprintf(params, contractname)
-	respBody, err := http.Post(addr, "application/x-www-form-urlencoded",
-		strings.NewReader(s))
+//resolveAbiType follows the alias chain of typ declared in abi.Types and
+//returns the underlying primitive or struct type name
+func resolveAbiType(abi *ABI, typ string) (string, error) {
+	seen := map[string]bool{}
+	for {
+		next := ""
+		for _, t := range abi.Types {
+			if t.NewTypeName == typ {
+				next = t.Type
+				break
+			}
+		}
+		if next == "" {
+			return typ, nil
+		}
 
-	if err != nil {
-		fmt.Println(err)
-		return ABI{}, err
+		if seen[typ] {
+			return "", fmt.Errorf("type alias cycle at %s", typ)
+		}
+		seen[typ] = true
+		typ = next
 	}
+}
 
-	defer respBody.Body.Close()
-	body, err := ioutil.ReadAll(respBody.Body)
-	if err != nil {
-		fmt.Println(err)
-		return ABI{}, err
+//getAbiVariant returns the abi variant named name, or nil
+func getAbiVariant(abi *ABI, name string) *ABIVariant {
+	for i := range abi.Variants {
+		if abi.Variants[i].Name == name {
+			return &abi.Variants[i]
+		}
 	}
+	return nil
+}
 
-	jss, _ := simplejson.NewJson([]byte(body))
-	abistring = jss.Get("result").MustString()
-	if len(abistring) <= 0 {
-		fmt.Println(err)
-		return ABI{}, err
+//getAbiStruct returns the abi struct named name, or nil
+func getAbiStruct(abi *ABI, name string) *ABIStruct {
+	for i := range abi.Structs {
+		if abi.Structs[i].Name == name {
+			return &abi.Structs[i]
+		}
+	}
+	return nil
+}
+
+//abiStructFields returns the fields of the abi struct named structname.
+//If the struct declares a base, the fields of the base come first,
+//recursively.
+func abiStructFields(abi *ABI, structname string) (*FeildMap, error) {
+	chain := []*ABIStruct{}
+	seen := map[string]bool{}
+	for name := structname; name != ""; {
+		if seen[name] {
+			return nil, fmt.Errorf("struct %s: base cycle at %s", structname, name)
+		}
+		seen[name] = true
+
+		st := getAbiStruct(abi, name)
+		if st == nil {
+			if name == structname {
+				return nil, fmt.Errorf("undefined struct %s", name)
+			}
+			return nil, fmt.Errorf("struct %s: undefined base %s", chain[len(chain)-1].Name, name)
+		}
+		chain = append(chain, st)
+		name = st.Base
+	}
+
+	if len(chain) == 1 && chain[0].Fields != nil {
+		return chain[0].Fields, nil
+	}
+
+	fields := New()
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Fields == nil {
+			continue
+		}
+		for _, k := range chain[i].Fields.Keys() {
+			if _, ok := fields.Get(k); ok {
+				return nil, fmt.Errorf("struct %s: field %s is also defined in a base", chain[i].Name, k)
+			}
+			v, _ := chain[i].Fields.Get(k)
+			fields.Set(k, v)
+		}
+	}
+	return fields, nil
+}
+
+//ParseAbiStrict parse abiraw to struct for contracts and validates it, see
+//ABI.Validate. Repeated keys in abiraw, such as a field declared twice in a
+//struct, are reported as well. It is ParseAbi with the ValidateAbi option.
+func ParseAbiStrict(abiRaw []byte) (*ABI, error) {
+	return ParseAbi(abiRaw, ValidateAbi)
+}
+
+//validateParsedAbi validates abi, parsed from abiRaw, and reports repeated
+//keys of abiRaw with the problems found by Validate
+func validateParsedAbi(abi *ABI, abiRaw []byte) error {
+	errs, err := duplicateJSONKeys(abiRaw)
+	if err != nil {
+		return err
 	}
+	if err = abi.Validate(); err != nil {
+		errs = append(errs, err.(AbiErrors)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
-	Abi, err := ParseAbi([]byte(abistring))
+//GetAbibyContractName fetches the abi of contractname from the local node at
+//DefaultAbiEndpoint. Use an HTTPAbiSource to choose the node, timeouts and retries.
+func GetAbibyContractName(contractname string) (ABI, error) {
+	Abi, err := NewHTTPAbiSource(DefaultAbiEndpoint).GetAbi(context.Background(), contractname)
 	if err != nil {
-		fmt.Println("Parse abistring", abistring, " to abi failed!")
 		return ABI{}, err
 	}
 
@@ -0,0 +1,518 @@
+package msgpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+const testAbi = `{
+	"types": [],
+	"structs": [
+		{"name": "transfer", "base": "", "fields": {"from": "string", "to": "string", "value": "uint64"}},
+		{"name": "fileinfo", "base": "", "fields": {"user_name": "string", "file_size": "uint64", "file_name": "string", "sign": "bytes"}},
+		{"name": "datafilereg", "base": "", "fields": {"file_hash": "string", "info": "fileinfo"}}
+	],
+	"actions": [
+		{"action_name": "transfer", "type": "transfer"},
+		{"action_name": "datafilereg", "type": "datafilereg"}
+	],
+	"tables": []
+}`
+
+func TestUnmarshalAbiEx(t *testing.T) {
+	type FileInfo struct {
+		UserName string
+		FileSize uint64
+		FileName string
+		Sign     []byte
+	}
+
+	type DatafileReg struct {
+		FileHash string
+		Info     FileInfo
+	}
+
+	fmt.Println("TestUnmarshalAbiEx...")
+
+	abi, err := ParseAbi([]byte(testAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := Marshal(DatafileReg{
+		FileHash: "12345678901234567890",
+		Info:     FileInfo{UserName: "testuser", FileSize: 111, FileName: "file", Sign: []byte{1, 2}},
+	})
+	fm, err := UnmarshalAbiEx(b, abi, "datafilemng", "datafilereg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	js, _ := json.Marshal(fm)
+	expect := `{"file_hash":"12345678901234567890","info":{"user_name":"testuser","file_size":111,"file_name":"file","sign":"AQI="}}`
+	if string(js) != expect {
+		t.Fatalf("UnmarshalAbiEx: %s", js)
+	}
+
+	if _, err = UnmarshalAbiEx(b, abi, "bottos", "transfer"); err == nil {
+		t.Fatal("UnmarshalAbiEx: expected fields number mismatch")
+	}
+
+	b, _ = Marshal(struct {
+		From  string
+		To    string
+		Value uint32
+	}{"a", "b", 1})
+	if _, err = UnmarshalAbiEx(b, abi, "bottos", "transfer"); err == nil {
+		t.Fatal("UnmarshalAbiEx: expected uint64 type mismatch")
+	}
+}
+
+func TestUnmarshalAbi(t *testing.T) {
+	type FileInfo struct {
+		UserName string `json:"user_name"`
+		FileSize uint64 `json:"file_size"`
+		FileName string `json:"file_name"`
+		Sign     []byte `json:"sign"`
+	}
+
+	// field order differs from the abi on purpose
+	type DatafileReg struct {
+		Info     *FileInfo `msgpack:"info"`
+		FileHash string    `json:"file_hash,omitempty"`
+	}
+
+	type Transfer struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Value uint32 `json:"value"`
+	}
+
+	fmt.Println("TestUnmarshalAbi...")
+
+	abi, err := ParseAbi([]byte(testAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := HexToBytes("dc0002da00143132333435363738393031323334353637383930dc0004da00087465737475736572cf000000000000006fda000466696c65c500020102")
+	ts := DatafileReg{}
+	err = UnmarshalAbi(b, &ts, abi, "datafilemng", "datafilereg")
+	if err != nil || ts.FileHash != "12345678901234567890" || ts.Info == nil || ts.Info.FileSize != 111 || ts.Info.FileName != "file" {
+		t.Fatalf("UnmarshalAbi: %v %v", ts, err)
+	}
+
+	// Go type mismatch: value is uint64 in the abi
+	b, _ = HexToBytes("dc0003da0006626f74746f73da0003626f74cf0000000000000064")
+	tr := Transfer{}
+	if err = UnmarshalAbi(b, &tr, abi, "bottos", "transfer"); err == nil {
+		t.Fatal("UnmarshalAbi: expected Go type mismatch")
+	}
+
+	// wire type mismatch: value is encoded as uint32
+	b, _ = HexToBytes("dc0003da0006626f74746f73da0003626f74ce00000064")
+	fm := struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Value uint64 `json:"value"`
+	}{}
+	if err = UnmarshalAbi(b, &fm, abi, "bottos", "transfer"); err == nil {
+		t.Fatal("UnmarshalAbi: expected wire type mismatch")
+	}
+}
+
+func TestEncodeAbiNestedByTypeName(t *testing.T) {
+	type FileInfo struct {
+		UserName string `json:"user_name"`
+		FileSize uint64 `json:"file_size"`
+		FileName string `json:"file_name"`
+		Sign     []byte `json:"sign"`
+	}
+
+	type FileCopy struct {
+		Src *FileInfo `json:"src"`
+		Dst FileInfo  `json:"dst"`
+	}
+
+	fmt.Println("TestEncodeAbiNestedByTypeName...")
+
+	abi, err := ParseAbi([]byte(`{
+		"structs": [
+			{"name": "fileinfo", "base": "", "fields": {"user_name": "string", "file_size": "uint64", "file_name": "string", "sign": "bytes"}},
+			{"name": "filecopy", "base": "", "fields": {"src": "fileinfo", "dst": "fileinfo"}},
+			{"name": "broken", "base": "", "fields": {"src": "fileinfo", "dst": "nosuchtype"}}
+		],
+		"actions": [
+			{"action_name": "filecopy", "type": "filecopy"},
+			{"action_name": "broken", "type": "broken"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fc := FileCopy{
+		Src: &FileInfo{UserName: "alice", FileSize: 1, FileName: "a", Sign: []byte{1}},
+		Dst: FileInfo{UserName: "bob", FileSize: 2, FileName: "b", Sign: []byte{2}},
+	}
+	b, err := MarshalAbi(fc, abi, "datafilemng", "filecopy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1, _ := Marshal(fc)
+	if BytesToHex(b) != BytesToHex(b1) {
+		t.Fatalf("MarshalAbi: %x != %x", b, b1)
+	}
+
+	b2, err := MarshalAbiEx(map[string]interface{}{
+		"src": map[string]interface{}{"user_name": "alice", "file_size": uint64(1), "file_name": "a", "sign": []byte{1}},
+		"dst": fc.Dst,
+	}, abi, "datafilemng", "filecopy")
+	if err != nil || BytesToHex(b2) != BytesToHex(b1) {
+		t.Fatalf("MarshalAbiEx: %x %v", b2, err)
+	}
+
+	fc1 := FileCopy{}
+	err = UnmarshalAbi(b, &fc1, abi, "datafilemng", "filecopy")
+	if err != nil || fc1.Src.UserName != "alice" || fc1.Dst.UserName != "bob" {
+		t.Fatalf("UnmarshalAbi: %v %v", fc1, err)
+	}
+
+	_, err = MarshalAbiEx(map[string]interface{}{
+		"src": fc.Dst,
+		"dst": fc.Dst,
+	}, abi, "datafilemng", "broken")
+	if err == nil {
+		t.Fatal("MarshalAbiEx: expected undefined type error")
+	}
+}
+
+func TestAbiBase(t *testing.T) {
+	type Transfer struct {
+		Sender string `json:"sender"`
+		Seq    uint32 `json:"seq"`
+		To     string `json:"to"`
+		Value  uint64 `json:"value"`
+	}
+
+	fmt.Println("TestAbiBase...")
+
+	abi, err := ParseAbi([]byte(`{
+		"structs": [
+			{"name": "header", "base": "", "fields": {"sender": "string"}},
+			{"name": "seqheader", "base": "header", "fields": {"seq": "uint32"}},
+			{"name": "transfer", "base": "seqheader", "fields": {"to": "string", "value": "uint64"}},
+			{"name": "cycle1", "base": "cycle2", "fields": {"a": "string"}},
+			{"name": "cycle2", "base": "cycle1", "fields": {"b": "string"}},
+			{"name": "orphan", "base": "nosuchbase", "fields": {"a": "string"}}
+		],
+		"actions": [
+			{"action_name": "transfer", "type": "transfer"},
+			{"action_name": "cycle", "type": "cycle1"},
+			{"action_name": "orphan", "type": "orphan"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := Transfer{Sender: "bottos", Seq: 1, To: "bot", Value: 100}
+	b, err := MarshalAbi(ts, abi, "bottos", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1, _ := Marshal(ts)
+	if BytesToHex(b) != BytesToHex(b1) {
+		t.Fatalf("MarshalAbi: %x != %x", b, b1)
+	}
+
+	fm, err := UnmarshalAbiEx(b, abi, "bottos", "transfer")
+	if err != nil || fmt.Sprint(fm.Keys()) != "[sender seq to value]" {
+		t.Fatalf("UnmarshalAbiEx: %v %v", fm, err)
+	}
+
+	for _, method := range []string{"cycle", "orphan"} {
+		_, err = MarshalAbiEx(map[string]interface{}{"a": "x", "b": "y"}, abi, "bottos", method)
+		if err == nil {
+			t.Fatalf("MarshalAbiEx %s: expected error", method)
+		}
+		fmt.Println(err)
+	}
+}
+
+func TestAbiTypeAlias(t *testing.T) {
+	type Transfer struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Value uint64 `json:"value"`
+	}
+
+	fmt.Println("TestAbiTypeAlias...")
+
+	abi, err := ParseAbi([]byte(`{
+		"types": [
+			{"new_type_name": "account_name", "type": "string"},
+			{"new_type_name": "receiver_name", "type": "account_name"},
+			{"new_type_name": "asset_id", "type": "uint64"},
+			{"new_type_name": "loop1", "type": "loop2"},
+			{"new_type_name": "loop2", "type": "loop1"}
+		],
+		"structs": [
+			{"name": "transfer", "base": "", "fields": {"from": "account_name", "to": "receiver_name", "value": "asset_id"}},
+			{"name": "loop", "base": "", "fields": {"from": "loop1"}}
+		],
+		"actions": [
+			{"action_name": "transfer", "type": "transfer"},
+			{"action_name": "loop", "type": "loop"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := Transfer{From: "bottos", To: "bot", Value: 100}
+	b, err := MarshalAbi(ts, abi, "bottos", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1, err := MarshalAbiEx(map[string]interface{}{"from": "bottos", "to": "bot", "value": uint64(100)}, abi, "bottos", "transfer")
+	if err != nil || BytesToHex(b) != BytesToHex(b1) {
+		t.Fatalf("MarshalAbiEx: %x %v", b1, err)
+	}
+
+	ts1 := Transfer{}
+	if err = UnmarshalAbi(b, &ts1, abi, "bottos", "transfer"); err != nil || ts1 != ts {
+		t.Fatalf("UnmarshalAbi: %v %v", ts1, err)
+	}
+
+	if _, err = MarshalAbiEx(map[string]interface{}{"from": "bottos"}, abi, "bottos", "loop"); err == nil {
+		t.Fatal("MarshalAbiEx: expected alias cycle error")
+	}
+}
+
+func TestAbiArrayOptional(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Value uint64 `json:"value"`
+	}
+
+	type BatchTransfer struct {
+		From   string   `json:"from"`
+		To     []string `json:"to"`
+		Values []uint64 `json:"values"`
+		Memo   *string  `json:"memo"`
+		Items  []Item   `json:"items"`
+		Extra  *Item    `json:"extra"`
+	}
+
+	fmt.Println("TestAbiArrayOptional...")
+
+	abi, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "account_name", "type": "string"}],
+		"structs": [
+			{"name": "item", "base": "", "fields": {"name": "string", "value": "uint64"}},
+			{"name": "batchtransfer", "base": "", "fields": {"from": "account_name", "to": "account_name[]", "values": "uint64[]", "memo": "string?", "items": "item[]", "extra": "item?"}}
+		],
+		"actions": [{"action_name": "batchtransfer", "type": "batchtransfer"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memo := "memo"
+	bt := BatchTransfer{
+		From:   "bottos",
+		To:     []string{"a", "b"},
+		Values: []uint64{1, 2},
+		Memo:   &memo,
+		Items:  []Item{{Name: "x", Value: 3}},
+	}
+	b, err := MarshalAbi(bt, abi, "bottos", "batchtransfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "dc0006da0006626f74746f73dc0002da000161da000162dc0002cf0000000000000001cf0000000000000002da00046d656d6fdc0001dc0002da000178cf0000000000000003c0"
+	if BytesToHex(b) != expect {
+		t.Fatalf("MarshalAbi: %x", b)
+	}
+
+	b1, err := MarshalAbiEx(map[string]interface{}{
+		"from":   "bottos",
+		"to":     []interface{}{"a", "b"},
+		"values": []uint64{1, 2},
+		"memo":   "memo",
+		"items":  []interface{}{map[string]interface{}{"name": "x", "value": uint64(3)}},
+		"extra":  nil,
+	}, abi, "bottos", "batchtransfer")
+	if err != nil || BytesToHex(b1) != expect {
+		t.Fatalf("MarshalAbiEx: %x %v", b1, err)
+	}
+
+	bt1 := BatchTransfer{}
+	err = UnmarshalAbi(b, &bt1, abi, "bottos", "batchtransfer")
+	if err != nil || *bt1.Memo != "memo" || bt1.Extra != nil || len(bt1.To) != 2 || bt1.Items[0].Value != 3 {
+		t.Fatalf("UnmarshalAbi: %v %v", bt1, err)
+	}
+
+	fm, err := UnmarshalAbiEx(b, abi, "bottos", "batchtransfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, _ := json.Marshal(fm)
+	if string(js) != `{"from":"bottos","to":["a","b"],"values":[1,2],"memo":"memo","items":[{"name":"x","value":3}],"extra":null}` {
+		t.Fatalf("UnmarshalAbiEx: %s", js)
+	}
+
+	// the array16 header holds at most 65535 items
+	bt.Values = make([]uint64, 0x10000)
+	if _, err = MarshalAbi(bt, abi, "bottos", "batchtransfer"); err == nil {
+		t.Fatal("MarshalAbi: expected array length error")
+	}
+	long := make([]interface{}, 0x10000)
+	for i := range long {
+		long[i] = "a"
+	}
+	params := map[string]interface{}{"from": "bottos", "to": long, "values": []uint64{}, "memo": nil, "items": []interface{}{}, "extra": nil}
+	if _, err = MarshalAbiEx(params, abi, "bottos", "batchtransfer"); err == nil {
+		t.Fatal("MarshalAbiEx: expected array length error")
+	}
+	c, err := CompileAbi(abi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Encode("batchtransfer", params); err == nil {
+		t.Fatal("Encode: expected array length error")
+	}
+	if _, err = c.Encode("batchtransfer", bt); err == nil {
+		t.Fatal("Encode struct: expected array length error")
+	}
+}
+
+func TestAbiVariant(t *testing.T) {
+	type ParamProposal struct {
+		Key   string `json:"key"`
+		Value uint64 `json:"value"`
+	}
+
+	type Propose struct {
+		Proposer string   `json:"proposer"`
+		Proposal *Variant `json:"proposal"`
+	}
+
+	fmt.Println("TestAbiVariant...")
+
+	abi, err := ParseAbi([]byte(`{
+		"structs": [
+			{"name": "textproposal", "base": "", "fields": {"title": "string"}},
+			{"name": "paramproposal", "base": "", "fields": {"key": "string", "value": "uint64"}},
+			{"name": "propose", "base": "", "fields": {"proposer": "string", "proposal": "proposal"}}
+		],
+		"variants": [{"name": "proposal", "types": ["textproposal", "paramproposal"]}],
+		"actions": [{"action_name": "propose", "type": "propose"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Propose{
+		Proposer: "bottos",
+		Proposal: &Variant{Type: "paramproposal", Value: ParamProposal{Key: "fee", Value: 10}},
+	}
+	b, err := MarshalAbi(p, abi, "gov", "propose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "dc0002da0006626f74746f73dc0002cc01dc0002da0003666565cf000000000000000a"
+	if BytesToHex(b) != expect {
+		t.Fatalf("MarshalAbi: %x", b)
+	}
+
+	b1, err := MarshalAbiEx(map[string]interface{}{
+		"proposer": "bottos",
+		"proposal": Variant{Type: "paramproposal", Value: map[string]interface{}{"key": "fee", "value": uint64(10)}},
+	}, abi, "gov", "propose")
+	if err != nil || BytesToHex(b1) != expect {
+		t.Fatalf("MarshalAbiEx: %x %v", b1, err)
+	}
+
+	p1 := Propose{}
+	err = UnmarshalAbi(b, &p1, abi, "gov", "propose")
+	if err != nil || p1.Proposal.Type != "paramproposal" {
+		t.Fatalf("UnmarshalAbi: %v %v", p1, err)
+	}
+	fm := p1.Proposal.Value.(*FeildMap)
+	if v, _ := fm.Get("value"); v != uint64(10) {
+		t.Fatalf("UnmarshalAbi: variant value %v", v)
+	}
+
+	_, err = MarshalAbi(Propose{Proposer: "bottos", Proposal: &Variant{Type: "nosuchproposal"}}, abi, "gov", "propose")
+	if err == nil {
+		t.Fatal("MarshalAbi: expected unknown variant type error")
+	}
+}
+
+func TestAbiValidate(t *testing.T) {
+	fmt.Println("TestAbiValidate...")
+
+	if _, err := ParseAbiStrict([]byte(testAbi)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ParseAbiStrict([]byte(`{
+		"types": [
+			{"new_type_name": "account_name", "type": "strnig"},
+			{"new_type_name": "loop1", "type": "loop2"},
+			{"new_type_name": "loop2", "type": "loop1"}
+		],
+		"structs": [
+			{"name": "transfer", "base": "", "fields": {"from": "account_name", "to": "string", "value": "uint46"}},
+			{"name": "transfer", "base": "", "fields": {"from": "string"}},
+			{"name": "header", "base": "header", "fields": {"seq": "uint32[]"}},
+			{"name": "", "base": "", "fields": {}},
+			{"name": "dup", "base": "", "fields": {"a": "string", "a": "uint8"}}
+		],
+		"actions": [
+			{"action_name": "transfer", "type": "transfer"},
+			{"action_name": "transfer", "type": "transfer"},
+			{"action_name": "issue", "type": "issue"}
+		]
+	}`))
+	errs, ok := err.(AbiErrors)
+	if !ok {
+		t.Fatalf("expected AbiErrors, got %v", err)
+	}
+	fmt.Println(errs)
+
+	expect := map[string]bool{
+		"$.types[0].type":           true,
+		"$.types[1].type":           true,
+		"$.types[2].type":           true,
+		"$.structs[0].fields.from":  true,
+		"$.structs[0].fields.value": true,
+		"$.structs[1].name":         true,
+		"$.structs[2].base":         true,
+		"$.structs[3].name":         true,
+		"$.structs[4].fields.a":     true,
+		"$.actions[1].action_name":  true,
+		"$.actions[2].type":         true,
+	}
+	for _, e := range errs {
+		if !expect[e.Path] {
+			t.Errorf("unexpected error %v", e)
+		}
+		delete(expect, e.Path)
+	}
+	for path := range expect {
+		t.Errorf("missing error at %s", path)
+	}
+
+	// ParseAbi validates only with the ValidateAbi option
+	typo := []byte(`{"structs": [{"name": "t", "base": "", "fields": {"a": "strnig"}}], "actions": [{"action_name": "t", "type": "t"}]}`)
+	if _, err = ParseAbi(typo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseAbi(typo, ValidateAbi); err == nil || err.(AbiErrors)[0].Path != "$.structs[0].fields.a" {
+		t.Fatalf("ParseAbi ValidateAbi: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package abigen
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+//abiIndex looks up the declarations of a validated abi
+type abiIndex struct {
+	abi *msgpack.ABI
+}
+
+func (x abiIndex) getStruct(name string) *msgpack.ABIStruct {
+	if name == "" {
+		return nil
+	}
+	for i := range x.abi.Structs {
+		if x.abi.Structs[i].Name == name {
+			return &x.abi.Structs[i]
+		}
+	}
+	return nil
+}
+
+func (x abiIndex) getAlias(name string) *msgpack.ABIType {
+	for i := range x.abi.Types {
+		if x.abi.Types[i].NewTypeName == name {
+			return &x.abi.Types[i]
+		}
+	}
+	return nil
+}
+
+func (x abiIndex) getVariant(name string) *msgpack.ABIVariant {
+	for i := range x.abi.Variants {
+		if x.abi.Variants[i].Name == name {
+			return &x.abi.Variants[i]
+		}
+	}
+	return nil
+}
+
+//resolve follows aliases until typ is a primitive, struct, variant, `T[]` or `T?`
+func (x abiIndex) resolve(typ string) string {
+	for {
+		alias := x.getAlias(typ)
+		if alias == nil {
+			return typ
+		}
+		typ = alias.Type
+	}
+}
+
+//fields returns the fields of s with base fields first, outermost base first
+func (x abiIndex) fields(s *msgpack.ABIStruct) []msgpack.StringPair {
+	var chain []*msgpack.ABIStruct
+	for cur := s; cur != nil; cur = x.getStruct(cur.Base) {
+		chain = append([]*msgpack.ABIStruct{cur}, chain...)
+	}
+
+	var pairs []msgpack.StringPair
+	for _, cur := range chain {
+		if cur.Fields != nil {
+			pairs = append(pairs, cur.Fields.GetStringPair()...)
+		}
+	}
+	return pairs
+}
+
+//jsString quotes s as a JavaScript string literal
+func jsString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,724 @@
+package abigen
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+//cppReserved are the identifiers of the C++ runtime
+var cppReserved = []string{"WriteStream", "ReadStream", "Bytes", "Optional"}
+
+var cppKeywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`alignas alignof and and_eq asm auto bitand bitor bool break case catch
+		char char16_t char32_t class compl const constexpr const_cast continue decltype default delete do
+		double dynamic_cast else enum explicit export extern false float for friend goto if inline int long
+		mutable namespace new noexcept not not_eq nullptr operator or or_eq private protected public
+		register reinterpret_cast return short signed sizeof static static_assert static_cast struct switch
+		template this thread_local throw true try typedef typeid typename union unsigned using virtual void
+		volatile wchar_t while xor xor_eq`) {
+		cppKeywords[k] = true
+	}
+}
+
+//GenerateCpp emits a self contained C++11 header declaring, in namespace ns,
+//one struct per abi struct and variant, one typedef per abi type, pack and
+//unpack overloads per struct with the layout of msgpack.Encode, and
+//encode_X and decode_X functions per action. Optionals are Optional<T>,
+//bytes is Bytes and variants hold a type index and one member per type.
+//Nothing throws or allocates outside the std::string and std::vector of
+//decoded values; every function reports failure by returning false or 0.
+func GenerateCpp(abi *msgpack.ABI, ns string) ([]byte, error) {
+	if abi == nil {
+		return nil, fmt.Errorf("abigen: abi is nil")
+	}
+	if err := abi.Validate(); err != nil {
+		return nil, err
+	}
+	if !cppIdent.MatchString(ns) || cppKeywords[ns] {
+		return nil, fmt.Errorf("abigen: namespace %q is not a C++ identifier", ns)
+	}
+
+	g := &cppGen{abiIndex: abiIndex{abi}, idents: map[string]string{}}
+	for _, ident := range cppReserved {
+		g.idents[ident] = "the runtime"
+	}
+	if err := g.generate(ns); err != nil {
+		return nil, err
+	}
+	return g.buf.Bytes(), nil
+}
+
+type cppGen struct {
+	abiIndex
+	buf    bytes.Buffer
+	idents map[string]string // C++ identifier -> what declared it
+}
+
+func (g *cppGen) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+//declare reserves a namespace level C++ identifier
+func (g *cppGen) declare(ident string, by string) error {
+	if prev, ok := g.idents[ident]; ok {
+		return fmt.Errorf("abigen: C++ name %s of %s collides with %s", ident, by, prev)
+	}
+	g.idents[ident] = by
+	return nil
+}
+
+//cppDecl is an abi type, variant or struct to be declared
+type cppDecl struct {
+	name  string
+	alias *msgpack.ABIType
+	vari  *msgpack.ABIVariant
+	strct *msgpack.ABIStruct
+}
+
+//deps are the named types decl needs complete before its declaration
+func (g *cppGen) deps(d cppDecl) []string {
+	var types []string
+	switch {
+	case d.alias != nil:
+		types = []string{d.alias.Type}
+	case d.vari != nil:
+		types = d.vari.Types
+	default:
+		for _, pair := range g.fields(d.strct) {
+			types = append(types, pair.Value)
+		}
+	}
+
+	var names []string
+	for _, typ := range types {
+		typ = strings.TrimRight(typ, "[]?")
+		if !isPrimitive(typ) {
+			names = append(names, typ)
+		}
+	}
+	return names
+}
+
+func isPrimitive(typ string) bool {
+	switch typ {
+	case "string", "uint8", "uint16", "uint32", "uint64", "bytes":
+		return true
+	}
+	return false
+}
+
+//ordered returns every declaration after the ones it depends on
+func (g *cppGen) ordered() ([]cppDecl, error) {
+	decls := map[string]cppDecl{}
+	var names []string
+	for i := range g.abi.Types {
+		d := cppDecl{name: g.abi.Types[i].NewTypeName, alias: &g.abi.Types[i]}
+		decls[d.name], names = d, append(names, d.name)
+	}
+	for i := range g.abi.Variants {
+		d := cppDecl{name: g.abi.Variants[i].Name, vari: &g.abi.Variants[i]}
+		decls[d.name], names = d, append(names, d.name)
+	}
+	for i := range g.abi.Structs {
+		d := cppDecl{name: g.abi.Structs[i].Name, strct: &g.abi.Structs[i]}
+		decls[d.name], names = d, append(names, d.name)
+	}
+
+	const visiting, done = 1, 2
+	state := map[string]int{}
+	var order []cppDecl
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("abigen: recursive type %s is not supported in C++", name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range g.deps(decls[name]) {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		order = append(order, decls[name])
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func (g *cppGen) generate(ns string) error {
+	decls, err := g.ordered()
+	if err != nil {
+		return err
+	}
+
+	guard := "MSGPACK_GEN_" + strings.ToUpper(ns) + "_HPP"
+	g.printf("// Code generated by msgpack gen-cpp. DO NOT EDIT.\n\n")
+	g.printf("#ifndef %s\n#define %s\n", guard, guard)
+	g.buf.WriteString(cppIncludes)
+	g.printf("\nnamespace %s {\n", ns)
+	g.buf.WriteString(cppRuntime)
+
+	for _, d := range decls {
+		name := goName(d.name)
+		switch {
+		case d.alias != nil:
+			if err := g.declare(name, "abi type "+d.name); err != nil {
+				return err
+			}
+			g.printf("\n// %s is the abi type %s.\ntypedef %s %s;\n", name, d.name, g.cppType(d.alias.Type), name)
+		case d.vari != nil:
+			if err := g.declare(name, "abi variant "+d.name); err != nil {
+				return err
+			}
+			g.printf("\n// %s is the abi variant %s, type selects the member.\nstruct %s {\n    uint8_t type;\n", name, d.name, name)
+			for i, typ := range d.vari.Types {
+				g.printf("    %s v%d; // %s\n", g.cppType(typ), i, typ)
+			}
+			g.printf("\n    %s() : type(0)", name)
+			for i := range d.vari.Types {
+				g.printf(", v%d()", i)
+			}
+			g.printf(" {}\n};\n")
+		default:
+			if err := g.declare(name, "abi struct "+d.name); err != nil {
+				return err
+			}
+			g.printf("\n// %s is the abi struct %s.\nstruct %s {\n", name, d.name, name)
+			fields := g.fields(d.strct)
+			seen := map[string]string{}
+			for _, pair := range fields {
+				field := cppField(pair.Key)
+				if prev, ok := seen[field]; ok {
+					return fmt.Errorf("abigen: C++ name %s of field %s.%s collides with field %s", field, d.name, pair.Key, prev)
+				}
+				seen[field] = pair.Key
+				g.printf("    %s %s;\n", g.cppType(pair.Value), field)
+			}
+			if len(fields) > 0 {
+				var inits []string
+				for _, pair := range fields {
+					inits = append(inits, cppField(pair.Key)+"()")
+				}
+				g.printf("\n    %s() : %s {}\n", name, strings.Join(inits, ", "))
+			}
+			g.printf("};\n")
+		}
+	}
+
+	g.printf("\n")
+	for _, d := range decls {
+		if d.alias == nil {
+			name := goName(d.name)
+			g.printf("inline bool pack(WriteStream& s, const %s& v);\ninline bool unpack(ReadStream& s, %s& v);\n", name, name)
+		}
+	}
+
+	for _, d := range decls {
+		name := goName(d.name)
+		switch {
+		case d.vari != nil:
+			g.printf("\ninline bool pack(WriteStream& s, const %s& v) {\n    if (!pack_array_size(s, 2) || !pack(s, v.type)) {\n        return false;\n    }\n    switch (v.type) {\n", name)
+			for i := range d.vari.Types {
+				g.printf("    case %d:\n        return pack(s, v.v%d);\n", i, i)
+			}
+			g.printf("    }\n    return false;\n}\n")
+
+			g.printf("\ninline bool unpack(ReadStream& s, %s& v) {\n    if (!expect_size(s, 2) || !unpack(s, v.type)) {\n        return false;\n    }\n    switch (v.type) {\n", name)
+			for i := range d.vari.Types {
+				g.printf("    case %d:\n        return unpack(s, v.v%d);\n", i, i)
+			}
+			g.printf("    }\n    return false;\n}\n")
+		case d.strct != nil:
+			fields := g.fields(d.strct)
+			if len(fields) == 0 {
+				g.printf("\ninline bool pack(WriteStream& s, const %s&) {\n    return pack_array_size(s, 0);\n}\n", name)
+				g.printf("\ninline bool unpack(ReadStream& s, %s&) {\n    return expect_size(s, 0);\n}\n", name)
+				continue
+			}
+			g.printf("\ninline bool pack(WriteStream& s, const %s& v) {\n    return pack_array_size(s, %d)", name, len(fields))
+			for _, pair := range fields {
+				g.printf("\n        && pack(s, v.%s)", cppField(pair.Key))
+			}
+			g.printf(";\n}\n")
+
+			g.printf("\ninline bool unpack(ReadStream& s, %s& v) {\n    return expect_size(s, %d)", name, len(fields))
+			for _, pair := range fields {
+				g.printf("\n        && unpack(s, v.%s)", cppField(pair.Key))
+			}
+			g.printf(";\n}\n")
+		}
+	}
+
+	for _, a := range g.abi.Actions {
+		typ := g.resolve(a.Type)
+		if g.getStruct(typ) == nil {
+			return fmt.Errorf("abigen: action %s: type %s is not a struct", a.ActionName, a.Type)
+		}
+
+		name := cppField(a.ActionName)
+		for _, ident := range []string{"encode_" + name, "decode_" + name} {
+			if err := g.declare(ident, "action "+a.ActionName); err != nil {
+				return err
+			}
+		}
+		g.printf(`
+// encode_%[1]s encodes the parameters of action %[2]s into buf, it returns
+// the encoded size, or 0 if buf is too small or v can not be encoded.
+inline size_t encode_%[1]s(const %[3]s& v, uint8_t* buf, size_t size) {
+    WriteStream s(buf, size);
+    return pack(s, v) ? s.pos : 0;
+}
+
+// decode_%[1]s decodes the parameters of action %[2]s, trailing bytes are
+// an error.
+inline bool decode_%[1]s(const uint8_t* data, size_t size, %[3]s& v) {
+    ReadStream s(data, size);
+    return unpack(s, v) && s.pos == s.size;
+}
+`, name, a.ActionName, goName(typ))
+	}
+
+	g.printf("\n} // namespace %s\n\n#endif // %s\n", ns, guard)
+	return nil
+}
+
+//cppType maps an abi type to its C++ type
+func (g *cppGen) cppType(typ string) string {
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		return "std::vector<" + g.cppType(strings.TrimSuffix(typ, "[]")) + ">"
+	case strings.HasSuffix(typ, "?"):
+		return "Optional<" + g.cppType(strings.TrimSuffix(typ, "?")) + ">"
+	}
+	switch typ {
+	case "string":
+		return "std::string"
+	case "uint8", "uint16", "uint32", "uint64":
+		return typ + "_t"
+	case "bytes":
+		return "Bytes"
+	}
+	return goName(typ)
+}
+
+var cppIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+//cppField turns an abi name into a C++ identifier
+func cppField(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	s := string(b)
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	if cppKeywords[s] {
+		s += "_"
+	}
+	return s
+}
+
+//cppString quotes s as a C++ string literal, escaping bytes in octal so that
+//no escape can run into the next character
+func cppString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\' || c == '?':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "\\%03o", c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+//GenerateCppVectors emits a C++ program checking the header from
+//GenerateCpp, included as header, against a zero and a max sample of each
+//action encoded by the Go encoder. It encodes each sample, compares the
+//bytes, decodes them back and re-encodes the result, and exits non zero on
+//any mismatch.
+func GenerateCppVectors(abi *msgpack.ABI, ns string, header string) ([]byte, error) {
+	vectors, err := sampleVectors(abi)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &cppGen{abiIndex: abiIndex{abi}}
+	g.printf("// Code generated by msgpack gen-cpp. DO NOT EDIT.\n\n")
+	g.printf("#include <cstdio>\n#include <cstring>\n#include <vector>\n\n#include %s\n", cppString(header))
+	g.printf(`
+static int failed = 0;
+
+static void check(bool ok, const char* action, const char* name, const char* what) {
+    if (!ok) {
+        std::printf("FAIL %%s/%%s: %%s\n", action, name, what);
+        failed++;
+    }
+}
+`)
+
+	for i, v := range vectors {
+		name := goName(v.typ)
+		action := cppField(v.action)
+		g.printf("\nstatic void vector%d() {\n    using namespace %s;\n\n    %s v;\n", i, ns, name)
+		tmp := 0
+		for _, line := range g.cppAssign(v.typ, "v", v.value, &tmp) {
+			g.printf("    %s\n", line)
+		}
+
+		var elems []string
+		for _, c := range v.data {
+			elems = append(elems, fmt.Sprintf("0x%02x", c))
+		}
+		g.printf(`
+    static const uint8_t expect[] = {%[5]s};
+    const char* action = %[1]s;
+    const char* name = %[2]s;
+    std::vector<uint8_t> buf(sizeof(expect) + 1);
+    size_t n = encode_%[3]s(v, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "encode");
+    check(encode_%[3]s(v, &buf[0], sizeof(expect) - 1) == 0, action, name, "encode into short buffer");
+
+    %[4]s d;
+    check(decode_%[3]s(expect, sizeof(expect), d), action, name, "decode");
+    n = encode_%[3]s(d, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "re-encode decoded");
+    check(!decode_%[3]s(expect, sizeof(expect) - 1, d), action, name, "decode truncated");
+}
+`, cppString(v.action), cppString(v.name), action, name, strings.Join(elems, ", "))
+	}
+
+	g.printf("\nint main() {\n")
+	for i := range vectors {
+		g.printf("    vector%d();\n", i)
+	}
+	g.printf("    if (failed == 0) {\n        std::printf(\"ok %d vectors\\n\");\n    }\n    return failed == 0 ? 0 : 1;\n}\n", len(vectors))
+	return g.buf.Bytes(), nil
+}
+
+//cppAssign returns the statements setting path to a sample value of abi
+//type typ
+func (g *cppGen) cppAssign(typ string, path string, value interface{}, tmp *int) []string {
+	typ = g.resolve(typ)
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		var lines []string
+		elemType := strings.TrimSuffix(typ, "[]")
+		for _, e := range value.([]interface{}) {
+			elem := fmt.Sprintf("e%d", *tmp)
+			*tmp++
+			lines = append(lines, "{", fmt.Sprintf("    %s %s;", g.cppType(elemType), elem))
+			for _, line := range g.cppAssign(elemType, elem, e, tmp) {
+				lines = append(lines, "    "+line)
+			}
+			lines = append(lines, fmt.Sprintf("    %s.push_back(%s);", path, elem), "}")
+		}
+		return lines
+	case strings.HasSuffix(typ, "?"):
+		if value == nil {
+			return nil
+		}
+		return append([]string{path + ".has = true;"}, g.cppAssign(strings.TrimSuffix(typ, "?"), path+".value", value, tmp)...)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{fmt.Sprintf("%s = %s;", path, cppString(v))}
+	case uint8, uint16, uint32:
+		return []string{fmt.Sprintf("%s = %dU;", path, v)}
+	case uint64:
+		return []string{fmt.Sprintf("%s = %dULL;", path, v)}
+	case []byte:
+		var lines []string
+		for _, c := range v {
+			lines = append(lines, fmt.Sprintf("%s.push_back(0x%02x);", path, c))
+		}
+		return lines
+	case msgpack.Variant:
+		vari := g.getVariant(typ)
+		for i, t := range vari.Types {
+			if t == v.Type {
+				lines := []string{fmt.Sprintf("%s.type = %d;", path, i)}
+				return append(lines, g.cppAssign(t, fmt.Sprintf("%s.v%d", path, i), v.Value, tmp)...)
+			}
+		}
+	case map[string]interface{}:
+		var lines []string
+		for _, pair := range g.fields(g.getStruct(typ)) {
+			lines = append(lines, g.cppAssign(pair.Value, path+"."+cppField(pair.Key), v[pair.Key], tmp)...)
+		}
+		return lines
+	}
+	panic(fmt.Sprintf("abigen: unexpected sample %T", value))
+}
+
+const cppIncludes = `
+#include <cstddef>
+#include <cstring>
+#include <stdint.h>
+#include <string>
+#include <vector>
+`
+
+const cppRuntime = `
+// WriteStream writes into a caller owned buffer.
+struct WriteStream {
+    uint8_t* data;
+    size_t size;
+    size_t pos;
+
+    WriteStream(uint8_t* d, size_t n) : data(d), size(n), pos(0) {}
+};
+
+// ReadStream reads from a caller owned buffer.
+struct ReadStream {
+    const uint8_t* data;
+    size_t size;
+    size_t pos;
+
+    ReadStream(const uint8_t* d, size_t n) : data(d), size(n), pos(0) {}
+};
+
+// Bytes is the abi type bytes, encoded as bin16 rather than as an array.
+struct Bytes : std::vector<uint8_t> {
+    Bytes() {}
+    Bytes(const uint8_t* p, size_t n) : std::vector<uint8_t>(p, p + n) {}
+};
+
+// Optional is the abi type T?, encoded as nil when has is false.
+template <typename T>
+struct Optional {
+    bool has;
+    T value;
+
+    Optional() : has(false), value() {}
+};
+
+inline bool put_byte(WriteStream& s, uint8_t c) {
+    if (s.pos >= s.size) {
+        return false;
+    }
+    s.data[s.pos++] = c;
+    return true;
+}
+
+inline bool put_uint(WriteStream& s, uint64_t v, int width) {
+    for (int i = width - 1; i >= 0; i--) {
+        if (!put_byte(s, uint8_t(v >> (8 * i)))) {
+            return false;
+        }
+    }
+    return true;
+}
+
+inline bool put_raw(WriteStream& s, uint8_t code, const void* p, size_t n) {
+    if (n > 0xffff || !put_byte(s, code) || !put_uint(s, n, 2) || s.size - s.pos < n) {
+        return false;
+    }
+    if (n > 0) {
+        std::memcpy(s.data + s.pos, p, n);
+    }
+    s.pos += n;
+    return true;
+}
+
+inline bool get_byte(ReadStream& s, uint8_t& c) {
+    if (s.pos >= s.size) {
+        return false;
+    }
+    c = s.data[s.pos++];
+    return true;
+}
+
+inline bool get_uint(ReadStream& s, uint8_t code, int width, uint64_t& v) {
+    uint8_t c;
+    if (!get_byte(s, c) || c != code) {
+        return false;
+    }
+    v = 0;
+    for (int i = 0; i < width; i++) {
+        if (!get_byte(s, c)) {
+            return false;
+        }
+        v = (v << 8) | c;
+    }
+    return true;
+}
+
+inline bool get_raw(ReadStream& s, uint8_t code, const uint8_t*& p, size_t& n) {
+    uint64_t length;
+    if (!get_uint(s, code, 2, length) || s.size - s.pos < length) {
+        return false;
+    }
+    p = s.data + s.pos;
+    n = size_t(length);
+    s.pos += n;
+    return true;
+}
+
+inline bool pack_nil(WriteStream& s) {
+    return put_byte(s, 0xc0);
+}
+
+inline bool pack_array_size(WriteStream& s, size_t n) {
+    return n <= 0xffff && put_byte(s, 0xdc) && put_uint(s, n, 2);
+}
+
+inline bool pack(WriteStream& s, uint8_t v) {
+    return put_byte(s, 0xcc) && put_uint(s, v, 1);
+}
+
+inline bool pack(WriteStream& s, uint16_t v) {
+    return put_byte(s, 0xcd) && put_uint(s, v, 2);
+}
+
+inline bool pack(WriteStream& s, uint32_t v) {
+    return put_byte(s, 0xce) && put_uint(s, v, 4);
+}
+
+inline bool pack(WriteStream& s, uint64_t v) {
+    return put_byte(s, 0xcf) && put_uint(s, v, 8);
+}
+
+inline bool pack(WriteStream& s, const std::string& v) {
+    return put_raw(s, 0xda, v.data(), v.size());
+}
+
+inline bool pack(WriteStream& s, const Bytes& v) {
+    return put_raw(s, 0xc5, v.empty() ? 0 : &v[0], v.size());
+}
+
+template <typename T>
+inline bool pack(WriteStream& s, const std::vector<T>& v) {
+    if (!pack_array_size(s, v.size())) {
+        return false;
+    }
+    for (size_t i = 0; i < v.size(); i++) {
+        if (!pack(s, v[i])) {
+            return false;
+        }
+    }
+    return true;
+}
+
+template <typename T>
+inline bool pack(WriteStream& s, const Optional<T>& v) {
+    return v.has ? pack(s, v.value) : pack_nil(s);
+}
+
+inline bool unpack_array_size(ReadStream& s, size_t& n) {
+    uint64_t v;
+    if (!get_uint(s, 0xdc, 2, v)) {
+        return false;
+    }
+    n = size_t(v);
+    return true;
+}
+
+inline bool expect_size(ReadStream& s, size_t n) {
+    size_t m;
+    return unpack_array_size(s, m) && m == n;
+}
+
+inline bool unpack(ReadStream& s, uint8_t& v) {
+    uint64_t u;
+    if (!get_uint(s, 0xcc, 1, u)) {
+        return false;
+    }
+    v = uint8_t(u);
+    return true;
+}
+
+inline bool unpack(ReadStream& s, uint16_t& v) {
+    uint64_t u;
+    if (!get_uint(s, 0xcd, 2, u)) {
+        return false;
+    }
+    v = uint16_t(u);
+    return true;
+}
+
+inline bool unpack(ReadStream& s, uint32_t& v) {
+    uint64_t u;
+    if (!get_uint(s, 0xce, 4, u)) {
+        return false;
+    }
+    v = uint32_t(u);
+    return true;
+}
+
+inline bool unpack(ReadStream& s, uint64_t& v) {
+    return get_uint(s, 0xcf, 8, v);
+}
+
+inline bool unpack(ReadStream& s, std::string& v) {
+    const uint8_t* p;
+    size_t n;
+    if (!get_raw(s, 0xda, p, n)) {
+        return false;
+    }
+    v.assign(reinterpret_cast<const char*>(p), n);
+    return true;
+}
+
+inline bool unpack(ReadStream& s, Bytes& v) {
+    const uint8_t* p;
+    size_t n;
+    if (!get_raw(s, 0xc5, p, n)) {
+        return false;
+    }
+    v.assign(p, p + n);
+    return true;
+}
+
+template <typename T>
+inline bool unpack(ReadStream& s, std::vector<T>& v) {
+    size_t n;
+    if (!unpack_array_size(s, n)) {
+        return false;
+    }
+    v.clear();
+    v.resize(n);
+    for (size_t i = 0; i < n; i++) {
+        if (!unpack(s, v[i])) {
+            return false;
+        }
+    }
+    return true;
+}
+
+template <typename T>
+inline bool unpack(ReadStream& s, Optional<T>& v) {
+    if (s.pos < s.size && s.data[s.pos] == 0xc0) {
+        s.pos++;
+        v = Optional<T>();
+        return true;
+    }
+    v.has = true;
+    return unpack(s, v.value);
+}
+`
@@ -0,0 +1,134 @@
+package abigen
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+func TestGenerateCppGolden(t *testing.T) {
+	abi, err := msgpack.LoadAbiFile("testdata/gentest.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := GenerateCpp(abi, "gentest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := GenerateCppVectors(abi, "gentest", "gentest.hpp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for file, src := range map[string][]byte{
+		"testdata/gentest.hpp":         header,
+		"testdata/gentest_vectors.cpp": vectors,
+	} {
+		golden, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(src) != string(golden) {
+			t.Fatalf("output differs from %s, run go generate ./abigen/...:\n%s", file, src)
+		}
+	}
+
+	for _, expect := range []string{
+		"    Optional<std::string> memo;\n",
+		"    Bytes data;\n",
+		"    std::vector<uint32_t> values;\n",
+		"inline size_t encode_batchtransfer(const Batchtransfer& v, uint8_t* buf, size_t size) {\n",
+		"inline bool decode_propose(const uint8_t* data, size_t size, Propose& v) {\n",
+	} {
+		if !strings.Contains(string(header), expect) {
+			t.Fatalf("GenerateCpp: missing %q", expect)
+		}
+	}
+}
+
+func TestGenerateCppVectorsRun(t *testing.T) {
+	gxx, err := exec.LookPath("g++")
+	if err != nil {
+		t.Skip("g++ not found")
+	}
+
+	dir, err := ioutil.TempDir("", "abigen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bin := filepath.Join(dir, "vectors")
+	out, err := exec.Command(gxx, "-std=c++11", "-Wall", "-Wextra", "-Werror", "-pedantic", "-o", bin, "testdata/gentest_vectors.cpp").CombinedOutput()
+	if err != nil {
+		t.Fatalf("g++: %v\n%s", err, out)
+	}
+	out, err = exec.Command(bin).CombinedOutput()
+	if err != nil || !strings.HasPrefix(string(out), "ok ") {
+		t.Fatalf("vectors: %v\n%s", err, out)
+	}
+}
+
+func TestGenerateCppNames(t *testing.T) {
+	abi, err := msgpack.ParseAbi([]byte(`{
+		"types": [{"new_type_name": "entries", "type": "entry[]"}],
+		"structs": [
+			{"name": "registry", "base": "", "fields": {"new": "entries", "user-name": "string", "2fa": "uint8[]"}},
+			{"name": "entry", "base": "", "fields": {}}
+		],
+		"actions": [{"action_name": "reg.add", "type": "registry"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := GenerateCpp(abi, "names")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"    Entries new_;\n",
+		"    std::string user_name;\n",
+		"    std::vector<uint8_t> _2fa;\n",
+		"inline size_t encode_reg_add(const Registry& v, uint8_t* buf, size_t size) {\n",
+	} {
+		if !strings.Contains(string(header), expect) {
+			t.Fatalf("GenerateCpp: missing %q in\n%s", expect, header)
+		}
+	}
+	// entry and the typedef entries have to be declared before registry
+	if !(strings.Index(string(header), "struct Entry {") < strings.Index(string(header), "typedef std::vector<Entry> Entries;") &&
+		strings.Index(string(header), "typedef std::vector<Entry> Entries;") < strings.Index(string(header), "struct Registry {")) {
+		t.Fatalf("GenerateCpp: declarations out of order\n%s", header)
+	}
+
+	if gxx, err := exec.LookPath("g++"); err == nil {
+		cmd := exec.Command(gxx, "-std=c++11", "-Wall", "-Wextra", "-Werror", "-fsyntax-only", "-x", "c++", "-")
+		cmd.Stdin = strings.NewReader(string(header))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("g++: %v\n%s", err, out)
+		}
+	}
+
+	if _, err = GenerateCpp(abi, "new"); err == nil {
+		t.Fatal("GenerateCpp: expected namespace error")
+	}
+}
+
+func TestGenerateCppRecursive(t *testing.T) {
+	abi, err := msgpack.ParseAbi([]byte(`{
+		"structs": [{"name": "node", "base": "", "fields": {"next": "node?"}}],
+		"actions": []
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GenerateCpp(abi, "tree"); err == nil || !strings.Contains(err.Error(), "recursive") {
+		t.Fatalf("GenerateCpp: expected recursive type error, got %v", err)
+	}
+}
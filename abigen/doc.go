@@ -0,0 +1,7 @@
+// Package abigen generates abis from Go source, and Go, TypeScript and C++
+// types with typed encode and decode functions from abis. Generated
+// TypeScript and C++ come with golden vectors from the Go encoder.
+package abigen
+
+//go:generate go run ../cmd/msgpack gen-ts -abi testdata/gentest.abi -o testdata/gentest.ts -vectors testdata/gentest.vectors.ts
+//go:generate go run ../cmd/msgpack gen-cpp -abi testdata/gentest.abi -ns gentest -o testdata/gentest.hpp -vectors testdata/gentest_vectors.cpp
@@ -0,0 +1,213 @@
+package abigen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+//GenerateGo emits a Go source file for package pkg with one type per abi
+//struct, one alias per abi type, and EncodeX and DecodeX helpers per action
+//built on a msgpack.CompiledAbi of the embedded abi. Base struct fields are
+//flattened into the struct, first.
+func GenerateGo(abi *msgpack.ABI, pkg string) ([]byte, error) {
+	if abi == nil {
+		return nil, fmt.Errorf("abigen: abi is nil")
+	}
+	if err := abi.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := &goGen{abiIndex: abiIndex{abi}, idents: map[string]string{}}
+	for _, ident := range []string{"Abi", "abiJSON", "compiledAbi"} {
+		g.idents[ident] = "generated code"
+	}
+	if err := g.generate(pkg); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("abigen: format generated code: %v", err)
+	}
+	return src, nil
+}
+
+type goGen struct {
+	abiIndex
+	buf    bytes.Buffer
+	idents map[string]string // Go identifier -> what declared it
+}
+
+func (g *goGen) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+//declare reserves a top level Go identifier
+func (g *goGen) declare(ident string, by string) error {
+	if prev, ok := g.idents[ident]; ok {
+		return fmt.Errorf("abigen: Go name %s of %s collides with %s", ident, by, prev)
+	}
+	g.idents[ident] = by
+	return nil
+}
+
+func (g *goGen) generate(pkg string) error {
+	js, err := json.Marshal(g.abi)
+	if err != nil {
+		return err
+	}
+	lit := "`" + string(js) + "`"
+	if strings.Contains(string(js), "`") {
+		lit = strconv.Quote(string(js))
+	}
+
+	g.printf("// Code generated by msgpack gen-go. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", pkg)
+	g.printf("import msgpack \"github.com/bottos-project/msgpack-go\"\n\n")
+	g.printf("const abiJSON = %s\n\n", lit)
+	g.printf(`var compiledAbi = func() *msgpack.CompiledAbi {
+	abi, err := msgpack.ParseAbi([]byte(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	c, err := msgpack.CompileAbi(abi)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}()
+
+// Abi returns the abi the code was generated from.
+func Abi() *msgpack.ABI {
+	return compiledAbi.Abi()
+}
+`)
+
+	for _, t := range g.abi.Types {
+		name := goName(t.NewTypeName)
+		if err := g.declare(name, "abi type "+t.NewTypeName); err != nil {
+			return err
+		}
+		g.printf("\n// %s is the abi type %s.\ntype %s = %s\n", name, t.NewTypeName, name, g.goType(t.Type))
+	}
+
+	for _, v := range g.abi.Variants {
+		name := goName(v.Name)
+		if err := g.declare(name, "abi variant "+v.Name); err != nil {
+			return err
+		}
+		g.printf("\n// %s is the abi variant %s of %s.\ntype %s = msgpack.Variant\n", name, v.Name, strings.Join(v.Types, ", "), name)
+	}
+
+	for i := range g.abi.Structs {
+		if err := g.generateStruct(&g.abi.Structs[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range g.abi.Actions {
+		if err := g.generateAction(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *goGen) generateStruct(s *msgpack.ABIStruct) error {
+	name := goName(s.Name)
+	if err := g.declare(name, "abi struct "+s.Name); err != nil {
+		return err
+	}
+
+	fields := map[string]string{}
+	g.printf("\n// %s is the abi struct %s.\ntype %s struct {\n", name, s.Name, name)
+	for _, pair := range g.fields(s) {
+		field := goName(pair.Key)
+		if prev, ok := fields[field]; ok {
+			return fmt.Errorf("abigen: Go name %s of field %s.%s collides with field %s", field, s.Name, pair.Key, prev)
+		}
+		fields[field] = pair.Key
+		g.printf("\t%s %s `json:%s`\n", field, g.goType(pair.Value), strconv.Quote(pair.Key))
+	}
+	g.printf("}\n")
+	return nil
+}
+
+func (g *goGen) generateAction(a msgpack.ABIAction) error {
+	typ := g.resolve(a.Type)
+	if g.getStruct(typ) == nil {
+		return fmt.Errorf("abigen: action %s: type %s is not a struct", a.ActionName, a.Type)
+	}
+
+	name := goName(a.ActionName)
+	typeName := goName(typ)
+	for _, ident := range []string{"Encode" + name, "Decode" + name} {
+		if err := g.declare(ident, "action "+a.ActionName); err != nil {
+			return err
+		}
+	}
+
+	g.printf(`
+// Encode%[1]s encodes the parameters of action %[2]s.
+func Encode%[1]s(v *%[3]s) ([]byte, error) {
+	return compiledAbi.Encode(%[4]s, v)
+}
+
+// Decode%[1]s decodes the parameters of action %[2]s.
+func Decode%[1]s(data []byte) (*%[3]s, error) {
+	v := &%[3]s{}
+	if err := compiledAbi.DecodeInto(%[4]s, data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+`, name, a.ActionName, typeName, strconv.Quote(a.ActionName))
+	return nil
+}
+
+//goType maps an abi type to its Go type, the inverse of msgpack.AbiFromStructs
+func (g *goGen) goType(typ string) string {
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		return "[]" + g.goType(strings.TrimSuffix(typ, "[]"))
+	case strings.HasSuffix(typ, "?"):
+		return "*" + g.goType(strings.TrimSuffix(typ, "?"))
+	}
+	switch typ {
+	case "string", "uint8", "uint16", "uint32", "uint64":
+		return typ
+	case "bytes":
+		return "[]byte"
+	}
+	return goName(typ)
+}
+
+//goName turns an abi name such as account_name into an exported Go
+//identifier such as AccountName
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package abigen
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+func TestGenerateGoGolden(t *testing.T) {
+	abi, err := msgpack.LoadAbiFile("testdata/gentest.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := GenerateGo(abi, "gentest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden, err := ioutil.ReadFile("internal/gentest/gentest.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != string(golden) {
+		t.Fatalf("GenerateGo output differs from internal/gentest/gentest.go, run go generate ./abigen/...:\n%s", src)
+	}
+}
+
+func TestGenerateGoCollision(t *testing.T) {
+	abi, err := msgpack.ParseAbi([]byte(`{
+		"structs": [
+			{"name": "user_info", "base": "", "fields": {"name": "string"}},
+			{"name": "userinfo", "base": "", "fields": {"name": "string"}},
+			{"name": "UserInfo", "base": "", "fields": {"name": "string"}}
+		],
+		"actions": []
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = GenerateGo(abi, "foo")
+	if err == nil || !strings.Contains(err.Error(), "collides") {
+		t.Fatalf("GenerateGo: expected collision error, got %v", err)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, expect := range map[string]string{
+		"transfer":     "Transfer",
+		"account_name": "AccountName",
+		"data.req-id":  "DataReqId",
+		"2fa":          "X2fa",
+	} {
+		if s := goName(name); s != expect {
+			t.Fatalf("goName(%q) = %q, want %q", name, s, expect)
+		}
+	}
+}
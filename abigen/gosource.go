@@ -0,0 +1,322 @@
+package abigen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+//FromGoFiles parses the Go files at paths, a directory standing for its non
+//test .go files, and builds an abi from the struct types named in actions,
+//keyed by action name. It follows the rules of msgpack.AbiFromStructs, so
+//the result is the same as reflecting over the compiled types.
+func FromGoFiles(paths []string, actions map[string]string) (*msgpack.ABI, error) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		names, err := goFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			f, err := parser.ParseFile(fset, name, nil, 0)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+	}
+	return FromSource(files, actions)
+}
+
+func goFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		names = append(names, filepath.Join(path, name))
+	}
+	return names, nil
+}
+
+//FromSource builds an abi from the struct types declared in files and named
+//in actions, keyed by action name. A type name may be qualified by its package
+//name, as in pkg.Type, and must be if files of several packages declare it.
+func FromSource(files []*ast.File, actions map[string]string) (*msgpack.ABI, error) {
+	g := &sourceGen{
+		decls: map[string]*typeDecl{},
+		names: map[string]string{},
+		abi: &msgpack.ABI{
+			Types:    []msgpack.ABIType{},
+			Structs:  []msgpack.ABIStruct{},
+			Variants: []msgpack.ABIVariant{},
+			Actions:  []msgpack.ABIAction{},
+			Tables:   []msgpack.ABITable{},
+		},
+	}
+	for _, f := range files {
+		pkg := f.Name.Name
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				d := &typeDecl{pkg: pkg, name: ts.Name.Name, typ: ts.Type, alias: ts.Assign.IsValid()}
+				if _, ok := g.decls[d.String()]; ok {
+					return nil, fmt.Errorf("abigen: type %s is declared twice", d)
+				}
+				g.decls[d.String()] = d
+			}
+		}
+	}
+
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		d, err := g.lookup(actions[name])
+		if err != nil {
+			return nil, fmt.Errorf("abigen: action %s: %v", name, err)
+		}
+		d = g.unalias(d)
+		st := g.structOf(d)
+		if st == nil {
+			return nil, fmt.Errorf("abigen: action %s: %s is not a struct type", name, actions[name])
+		}
+
+		structName := msgpack.AbiStructName(d.name)
+		if err := g.addStruct(structName, d, st); err != nil {
+			return nil, fmt.Errorf("abigen: action %s: %v", name, err)
+		}
+		g.abi.Actions = append(g.abi.Actions, msgpack.ABIAction{ActionName: name, Type: structName})
+	}
+
+	if err := g.abi.Validate(); err != nil {
+		return nil, err
+	}
+	return g.abi, nil
+}
+
+//typeDecl is a type declared in package pkg
+type typeDecl struct {
+	pkg   string
+	name  string
+	typ   ast.Expr
+	alias bool // type name = typ
+}
+
+func (d *typeDecl) String() string {
+	return d.pkg + "." + d.name
+}
+
+type sourceGen struct {
+	abi   *msgpack.ABI
+	decls map[string]*typeDecl // pkg.Name -> declaration
+	names map[string]string    // abi struct name -> pkg.Name of the Go type
+}
+
+//lookup finds the type named typeName, Type or pkg.Type
+func (g *sourceGen) lookup(typeName string) (*typeDecl, error) {
+	if strings.Contains(typeName, ".") {
+		if d, ok := g.decls[typeName]; ok {
+			return d, nil
+		}
+		return nil, fmt.Errorf("type %s not found", typeName)
+	}
+
+	var found []string
+	for key := range g.decls {
+		if strings.HasSuffix(key, "."+typeName) {
+			found = append(found, key)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("type %s not found", typeName)
+	case 1:
+		return g.decls[found[0]], nil
+	}
+	sort.Strings(found)
+	return nil, fmt.Errorf("type %s is ambiguous, use one of %s", typeName, strings.Join(found, ", "))
+}
+
+//unalias follows `type A = B` declarations within a package
+func (g *sourceGen) unalias(d *typeDecl) *typeDecl {
+	for seen := map[*typeDecl]bool{}; d.alias && !seen[d]; {
+		seen[d] = true
+		ident, ok := unparen(d.typ).(*ast.Ident)
+		if !ok {
+			break
+		}
+		next, ok := g.decls[d.pkg+"."+ident.Name]
+		if !ok {
+			break
+		}
+		d = next
+	}
+	return d
+}
+
+//structOf returns the struct type underlying d, following types defined from
+//other types of the same package as in `type T2 T1`, or nil
+func (g *sourceGen) structOf(d *typeDecl) *ast.StructType {
+	for seen := map[*typeDecl]bool{}; !seen[d]; {
+		seen[d] = true
+		switch t := unparen(d.typ).(type) {
+		case *ast.StructType:
+			return t
+		case *ast.Ident:
+			next, ok := g.decls[d.pkg+"."+t.Name]
+			if !ok {
+				return nil
+			}
+			d = next
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		p, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.X
+	}
+}
+
+func (g *sourceGen) addStruct(name string, d *typeDecl, st *ast.StructType) error {
+	if prev, ok := g.names[name]; ok {
+		if prev != d.String() {
+			return fmt.Errorf("struct name %s used by both %s and %s", name, prev, d)
+		}
+		return nil
+	}
+	g.names[name] = d.String()
+
+	fields := msgpack.New()
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			return fmt.Errorf("%s: embedded fields are not supported", d.name)
+		}
+
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			s, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return fmt.Errorf("%s: bad tag %s", d.name, field.Tag.Value)
+			}
+			tag = reflect.StructTag(s)
+		}
+
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				return fmt.Errorf("%s.%s is unexported", d.name, ident.Name)
+			}
+			typ, err := g.abiType(d.pkg, field.Type)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", d.name, ident.Name, err)
+			}
+			fieldName := msgpack.AbiFieldName(ident.Name, tag)
+			if _, ok := fields.Get(fieldName); ok {
+				return fmt.Errorf("%s: duplicate field %s", d.name, fieldName)
+			}
+			fields.Set(fieldName, typ)
+		}
+	}
+
+	g.abi.Structs = append(g.abi.Structs, msgpack.ABIStruct{Name: name, Fields: fields})
+	return nil
+}
+
+//abiType maps expr, written in package pkg, to an abi type
+func (g *sourceGen) abiType(pkg string, expr ast.Expr) (string, error) {
+	switch t := expr.(type) {
+	case *ast.ParenExpr:
+		return g.abiType(pkg, t.X)
+	case *ast.Ident:
+		d, ok := g.decls[pkg+"."+t.Name]
+		if !ok {
+			if typ, ok := msgpack.AbiBasicType(t.Name); ok {
+				return typ, nil
+			}
+			return "", fmt.Errorf("unsupported type %s", t.Name)
+		}
+		d = g.unalias(d)
+		if st := g.structOf(d); st != nil {
+			name := msgpack.AbiStructName(d.name)
+			return name, g.addStruct(name, d, st)
+		}
+		// a defined type such as `type AccountName string` is its underlying type
+		return g.abiType(d.pkg, d.typ)
+	case *ast.StarExpr:
+		if _, ok := unparen(t.X).(*ast.StarExpr); ok {
+			return "", fmt.Errorf("unsupported type %s", exprString(t))
+		}
+		elem, err := g.abiType(pkg, t.X)
+		return elem + "?", err
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return "", fmt.Errorf("unsupported type %s", exprString(t))
+		}
+		elem, err := g.abiType(pkg, t.Elt)
+		if elem == "uint8" {
+			return "bytes", err
+		}
+		return elem + "[]", err
+	case *ast.StructType:
+		return "", fmt.Errorf("anonymous struct %s", exprString(t))
+	}
+	return "", fmt.Errorf("unsupported type %s", exprString(expr))
+}
+
+func exprString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(t.X)
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return "[...]" + exprString(t.Elt)
+		}
+		return "[]" + exprString(t.Elt)
+	case *ast.StructType:
+		return "struct{...}"
+	}
+	return fmt.Sprintf("%T", expr)
+}
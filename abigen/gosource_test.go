@@ -0,0 +1,137 @@
+package abigen
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+type AccountName string
+
+type Item struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+type BatchTransfer struct {
+	From   AccountName   `json:"from"`
+	To     []AccountName `json:"to"`
+	Memo   *string       `json:"memo,omitempty"`
+	Items  []Item        `msgpack:"items" json:"item_list"`
+	Extra  *Item         `json:"extra"`
+	Data   []byte        `json:"data"`
+	Amount uint32
+}
+
+// Wrapped is defined from a struct type and named after itself, ItemAlias is
+// an alias and named after Item
+type Wrapped Item
+
+type ItemAlias = Item
+
+type Wrapper struct {
+	W  Wrapped   `json:"w"`
+	A  ItemAlias `json:"a"`
+	WS []Wrapped `json:"ws"`
+}
+
+const testSource = `package test
+
+type AccountName string
+
+type Item struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Value uint64 ` + "`json:\"value\"`" + `
+}
+
+type BatchTransfer struct {
+	From   AccountName   ` + "`json:\"from\"`" + `
+	To     []AccountName ` + "`json:\"to\"`" + `
+	Memo   *string       ` + "`json:\"memo,omitempty\"`" + `
+	Items  []Item        ` + "`msgpack:\"items\" json:\"item_list\"`" + `
+	Extra  *Item         ` + "`json:\"extra\"`" + `
+	Data   []byte        ` + "`json:\"data\"`" + `
+	Amount uint32
+}
+
+type Wrapped Item
+
+type ItemAlias = Item
+
+type Wrapper struct {
+	W  Wrapped   ` + "`json:\"w\"`" + `
+	A  ItemAlias ` + "`json:\"a\"`" + `
+	WS []Wrapped ` + "`json:\"ws\"`" + `
+}
+`
+
+func TestFromSource(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "test.go", testSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	abi, err := FromSource([]*ast.File{f}, map[string]string{"batchtransfer": "BatchTransfer", "additem": "Item", "wrap": "Wrapper", "wrapped": "Wrapped", "alias": "ItemAlias"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, err := msgpack.AbiFromStructs(map[string]interface{}{"batchtransfer": BatchTransfer{}, "additem": Item{}, "wrap": Wrapper{}, "wrapped": Wrapped{}, "alias": ItemAlias{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	js, _ := json.Marshal(abi)
+	js1, _ := json.Marshal(expect)
+	if string(js) != string(js1) {
+		t.Fatalf("FromSource: %s\nAbiFromStructs: %s", js, js1)
+	}
+}
+
+func TestFromSourceErrors(t *testing.T) {
+	for _, src := range []string{
+		"package test\ntype T struct{ V int64 }",
+		"package test\ntype T struct{ v string }",
+		"package test\ntype T struct{ V struct{ W string } }",
+		"package test\ntype T struct{ V [4]byte }",
+		"package test\ntype T struct{ Item }\ntype Item struct{}",
+		"package test\ntype T string",
+	} {
+		f, err := parser.ParseFile(token.NewFileSet(), "test.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = FromSource([]*ast.File{f}, map[string]string{"action": "T"}); err == nil {
+			t.Fatalf("FromSource %q: expected error", src)
+		}
+	}
+}
+
+func TestFromSourcePackages(t *testing.T) {
+	var files []*ast.File
+	for _, src := range []string{
+		"package a\ntype T struct{ V string }",
+		"package b\ntype T struct{ V uint8 }\ntype U struct{ T T }",
+	} {
+		f, err := parser.ParseFile(token.NewFileSet(), "test.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	if _, err := FromSource(files, map[string]string{"action": "T"}); err == nil {
+		t.Fatal("FromSource: expected ambiguous type error")
+	}
+	abi, err := FromSource(files, map[string]string{"action": "b.U"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, _ := json.Marshal(abi.Structs)
+	if string(js) != `[{"name":"t","base":"","fields":{"V":"uint8"}},{"name":"u","base":"","fields":{"T":"t"}}]` {
+		t.Fatalf("FromSource: %s", js)
+	}
+}
@@ -0,0 +1,6 @@
+// Package gentest is code generated by msgpack gen-go from
+// ../../testdata/gentest.abi, checked in as the golden output of
+// abigen.GenerateGo and tested as a client package would use it.
+package gentest
+
+//go:generate go run ../../../cmd/msgpack gen-go -abi ../../testdata/gentest.abi -pkg gentest -o gentest.go
@@ -0,0 +1,75 @@
+package gentest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+func TestBatchtransfer(t *testing.T) {
+	memo := "memo"
+	bt := &Batchtransfer{
+		From:   "bottos",
+		To:     []AccountName{"a", "b"},
+		Values: []uint32{1, 2},
+		Memo:   &memo,
+		Items:  []Item{{Name: "x", Value: 3}},
+		Data:   []byte{0xff},
+		Flag:   1,
+		Kind:   2,
+	}
+	b, err := EncodeBatchtransfer(bt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect, err := msgpack.MarshalAbiEx(map[string]interface{}{
+		"from":   "bottos",
+		"to":     []string{"a", "b"},
+		"values": []uint32{1, 2},
+		"memo":   "memo",
+		"items":  []interface{}{map[string]interface{}{"name": "x", "value": uint64(3)}},
+		"extra":  nil,
+		"data":   []byte{0xff},
+		"flag":   uint8(1),
+		"kind":   uint16(2),
+	}, Abi(), "gentest", "batchtransfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, expect) {
+		t.Fatalf("EncodeBatchtransfer: %x, want %x", b, expect)
+	}
+
+	bt1, err := DecodeBatchtransfer(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bt1.From != "bottos" || *bt1.Memo != "memo" || bt1.Extra != nil || bt1.Items[0].Value != 3 || bt1.Kind != 2 {
+		t.Fatalf("DecodeBatchtransfer: %+v", bt1)
+	}
+}
+
+func TestPropose(t *testing.T) {
+	p := &Propose{
+		Proposer: "bottos",
+		Proposal: Proposal{Type: "paramproposal", Value: Paramproposal{Key: "fee", Value: 10}},
+	}
+	b, err := EncodePropose(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(b) != "dc0002da0006626f74746f73dc0002cc01dc0002da0003666565cf000000000000000a" {
+		t.Fatalf("EncodePropose: %x", b)
+	}
+
+	p1, err := DecodePropose(b)
+	if err != nil || p1.Proposal.Type != "paramproposal" {
+		t.Fatalf("DecodePropose: %+v %v", p1, err)
+	}
+	if _, err = DecodePropose(b[:10]); err == nil {
+		t.Fatal("DecodePropose: expected error on truncated data")
+	}
+}
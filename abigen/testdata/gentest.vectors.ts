@@ -0,0 +1,29 @@
+// Code generated by msgpack gen-ts. DO NOT EDIT.
+
+/** Sample parameters of each action and their encoding by the Go encoder. */
+export const vectors = [
+  {
+    action: "batchtransfer",
+    name: "zero",
+    hex: "dc0009da0000dc0000dc0000c0dc0000c0c50000cc00cd0000",
+    value: { from: "", to: [], values: [], memo: null, items: [], extra: null, data: new Uint8Array([]), flag: 0, kind: 0 },
+  },
+  {
+    action: "batchtransfer",
+    name: "max",
+    hex: "dc0009da001168c3a96c6c6f2c20e4b896e7958c20225cdc0002da001168c3a96c6c6f2c20e4b896e7958c20225cda001168c3a96c6c6f2c20e4b896e7958c20225cdc0002ceffffffffceffffffffda001168c3a96c6c6f2c20e4b896e7958c20225cdc0002dc0002da001168c3a96c6c6f2c20e4b896e7958c20225ccfffffffffffffffffdc0002da001168c3a96c6c6f2c20e4b896e7958c20225ccfffffffffffffffffdc0002da001168c3a96c6c6f2c20e4b896e7958c20225ccfffffffffffffffffc50003007fffccffcdffff",
+    value: { from: "héllo, 世界 \"\\", to: ["héllo, 世界 \"\\", "héllo, 世界 \"\\"], values: [4294967295, 4294967295], memo: "héllo, 世界 \"\\", items: [{ name: "héllo, 世界 \"\\", value: 18446744073709551615n }, { name: "héllo, 世界 \"\\", value: 18446744073709551615n }], extra: { name: "héllo, 世界 \"\\", value: 18446744073709551615n }, data: new Uint8Array([0, 127, 255]), flag: 255, kind: 65535 },
+  },
+  {
+    action: "propose",
+    name: "zero",
+    hex: "dc0002da0000dc0002cc00dc0001da0000",
+    value: { proposer: "", proposal: { type: "textproposal", value: { title: "" } } },
+  },
+  {
+    action: "propose",
+    name: "max",
+    hex: "dc0002da001168c3a96c6c6f2c20e4b896e7958c20225cdc0002cc01dc0002da001168c3a96c6c6f2c20e4b896e7958c20225ccfffffffffffffffff",
+    value: { proposer: "héllo, 世界 \"\\", proposal: { type: "paramproposal", value: { key: "héllo, 世界 \"\\", value: 18446744073709551615n } } },
+  },
+];
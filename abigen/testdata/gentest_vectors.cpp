@@ -0,0 +1,160 @@
+// Code generated by msgpack gen-cpp. DO NOT EDIT.
+
+#include <cstdio>
+#include <cstring>
+#include <vector>
+
+#include "gentest.hpp"
+
+static int failed = 0;
+
+static void check(bool ok, const char* action, const char* name, const char* what) {
+    if (!ok) {
+        std::printf("FAIL %s/%s: %s\n", action, name, what);
+        failed++;
+    }
+}
+
+static void vector0() {
+    using namespace gentest;
+
+    Batchtransfer v;
+    v.from = "";
+    v.flag = 0U;
+    v.kind = 0U;
+
+    static const uint8_t expect[] = {0xdc, 0x00, 0x09, 0xda, 0x00, 0x00, 0xdc, 0x00, 0x00, 0xdc, 0x00, 0x00, 0xc0, 0xdc, 0x00, 0x00, 0xc0, 0xc5, 0x00, 0x00, 0xcc, 0x00, 0xcd, 0x00, 0x00};
+    const char* action = "batchtransfer";
+    const char* name = "zero";
+    std::vector<uint8_t> buf(sizeof(expect) + 1);
+    size_t n = encode_batchtransfer(v, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "encode");
+    check(encode_batchtransfer(v, &buf[0], sizeof(expect) - 1) == 0, action, name, "encode into short buffer");
+
+    Batchtransfer d;
+    check(decode_batchtransfer(expect, sizeof(expect), d), action, name, "decode");
+    n = encode_batchtransfer(d, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "re-encode decoded");
+    check(!decode_batchtransfer(expect, sizeof(expect) - 1, d), action, name, "decode truncated");
+}
+
+static void vector1() {
+    using namespace gentest;
+
+    Batchtransfer v;
+    v.from = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+    {
+        AccountName e0;
+        e0 = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+        v.to.push_back(e0);
+    }
+    {
+        AccountName e1;
+        e1 = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+        v.to.push_back(e1);
+    }
+    {
+        uint32_t e2;
+        e2 = 4294967295U;
+        v.values.push_back(e2);
+    }
+    {
+        uint32_t e3;
+        e3 = 4294967295U;
+        v.values.push_back(e3);
+    }
+    v.memo.has = true;
+    v.memo.value = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+    {
+        Item e4;
+        e4.name = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+        e4.value = 18446744073709551615ULL;
+        v.items.push_back(e4);
+    }
+    {
+        Item e5;
+        e5.name = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+        e5.value = 18446744073709551615ULL;
+        v.items.push_back(e5);
+    }
+    v.extra.has = true;
+    v.extra.value.name = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+    v.extra.value.value = 18446744073709551615ULL;
+    v.data.push_back(0x00);
+    v.data.push_back(0x7f);
+    v.data.push_back(0xff);
+    v.flag = 255U;
+    v.kind = 65535U;
+
+    static const uint8_t expect[] = {0xdc, 0x00, 0x09, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xdc, 0x00, 0x02, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xdc, 0x00, 0x02, 0xce, 0xff, 0xff, 0xff, 0xff, 0xce, 0xff, 0xff, 0xff, 0xff, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xdc, 0x00, 0x02, 0xdc, 0x00, 0x02, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xdc, 0x00, 0x02, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xdc, 0x00, 0x02, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xc5, 0x00, 0x03, 0x00, 0x7f, 0xff, 0xcc, 0xff, 0xcd, 0xff, 0xff};
+    const char* action = "batchtransfer";
+    const char* name = "max";
+    std::vector<uint8_t> buf(sizeof(expect) + 1);
+    size_t n = encode_batchtransfer(v, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "encode");
+    check(encode_batchtransfer(v, &buf[0], sizeof(expect) - 1) == 0, action, name, "encode into short buffer");
+
+    Batchtransfer d;
+    check(decode_batchtransfer(expect, sizeof(expect), d), action, name, "decode");
+    n = encode_batchtransfer(d, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "re-encode decoded");
+    check(!decode_batchtransfer(expect, sizeof(expect) - 1, d), action, name, "decode truncated");
+}
+
+static void vector2() {
+    using namespace gentest;
+
+    Propose v;
+    v.proposer = "";
+    v.proposal.type = 0;
+    v.proposal.v0.title = "";
+
+    static const uint8_t expect[] = {0xdc, 0x00, 0x02, 0xda, 0x00, 0x00, 0xdc, 0x00, 0x02, 0xcc, 0x00, 0xdc, 0x00, 0x01, 0xda, 0x00, 0x00};
+    const char* action = "propose";
+    const char* name = "zero";
+    std::vector<uint8_t> buf(sizeof(expect) + 1);
+    size_t n = encode_propose(v, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "encode");
+    check(encode_propose(v, &buf[0], sizeof(expect) - 1) == 0, action, name, "encode into short buffer");
+
+    Propose d;
+    check(decode_propose(expect, sizeof(expect), d), action, name, "decode");
+    n = encode_propose(d, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "re-encode decoded");
+    check(!decode_propose(expect, sizeof(expect) - 1, d), action, name, "decode truncated");
+}
+
+static void vector3() {
+    using namespace gentest;
+
+    Propose v;
+    v.proposer = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+    v.proposal.type = 1;
+    v.proposal.v1.key = "h\303\251llo, \344\270\226\347\225\214 \"\\";
+    v.proposal.v1.value = 18446744073709551615ULL;
+
+    static const uint8_t expect[] = {0xdc, 0x00, 0x02, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xdc, 0x00, 0x02, 0xcc, 0x01, 0xdc, 0x00, 0x02, 0xda, 0x00, 0x11, 0x68, 0xc3, 0xa9, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x20, 0x22, 0x5c, 0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff};
+    const char* action = "propose";
+    const char* name = "max";
+    std::vector<uint8_t> buf(sizeof(expect) + 1);
+    size_t n = encode_propose(v, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "encode");
+    check(encode_propose(v, &buf[0], sizeof(expect) - 1) == 0, action, name, "encode into short buffer");
+
+    Propose d;
+    check(decode_propose(expect, sizeof(expect), d), action, name, "decode");
+    n = encode_propose(d, &buf[0], buf.size());
+    check(n == sizeof(expect) && std::memcmp(&buf[0], expect, n) == 0, action, name, "re-encode decoded");
+    check(!decode_propose(expect, sizeof(expect) - 1, d), action, name, "decode truncated");
+}
+
+int main() {
+    vector0();
+    vector1();
+    vector2();
+    vector3();
+    if (failed == 0) {
+        std::printf("ok 4 vectors\n");
+    }
+    return failed == 0 ? 0 : 1;
+}
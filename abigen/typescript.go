@@ -0,0 +1,534 @@
+package abigen
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+//tsReserved are the identifiers of the TypeScript runtime and globals it uses
+var tsReserved = []string{"Writer", "Reader", "Error", "RangeError", "Number", "BigInt", "Uint8Array", "TextEncoder", "TextDecoder", "actions"}
+
+//GenerateTypeScript emits a TypeScript module with one interface per abi
+//struct, one type per abi type and variant, and encodeX and decodeX
+//functions per action. The module carries its own codec for the subset
+//pack.go writes: array16, str16, bin16 and uint8 to uint64, with uint64 as
+//bigint and bytes as Uint8Array. Optionals are `T | null` and variants
+//`{ type, value }` unions tagged by the declared type name.
+func GenerateTypeScript(abi *msgpack.ABI) ([]byte, error) {
+	if abi == nil {
+		return nil, fmt.Errorf("abigen: abi is nil")
+	}
+	if err := abi.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := &tsGen{abiIndex: abiIndex{abi}, idents: map[string]string{}}
+	for _, ident := range tsReserved {
+		g.idents[ident] = "the runtime"
+	}
+	if err := g.generate(); err != nil {
+		return nil, err
+	}
+	return g.buf.Bytes(), nil
+}
+
+type tsGen struct {
+	abiIndex
+	buf    bytes.Buffer
+	idents map[string]string // TypeScript identifier -> what declared it
+}
+
+func (g *tsGen) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+//declare reserves a top level TypeScript identifier
+func (g *tsGen) declare(ident string, by string) error {
+	if prev, ok := g.idents[ident]; ok {
+		return fmt.Errorf("abigen: TypeScript name %s of %s collides with %s", ident, by, prev)
+	}
+	g.idents[ident] = by
+	return nil
+}
+
+func (g *tsGen) generate() error {
+	g.printf("// Code generated by msgpack gen-ts. DO NOT EDIT.\n")
+
+	for _, t := range g.abi.Types {
+		name := goName(t.NewTypeName)
+		if err := g.declare(name, "abi type "+t.NewTypeName); err != nil {
+			return err
+		}
+		g.printf("\n/** The abi type %s. */\nexport type %s = %s;\n", t.NewTypeName, name, g.tsType(t.Type))
+	}
+
+	for _, v := range g.abi.Variants {
+		name := goName(v.Name)
+		for _, ident := range []string{name, "write" + name, "read" + name} {
+			if err := g.declare(ident, "abi variant "+v.Name); err != nil {
+				return err
+			}
+		}
+		g.printf("\n/** The abi variant %s. */\nexport type %s =\n", v.Name, name)
+		for i, typ := range v.Types {
+			end := ""
+			if i == len(v.Types)-1 {
+				end = ";"
+			}
+			g.printf("  | { type: %s; value: %s }%s\n", jsString(typ), g.tsType(typ), end)
+		}
+	}
+
+	for i := range g.abi.Structs {
+		s := &g.abi.Structs[i]
+		name := goName(s.Name)
+		for _, ident := range []string{name, "write" + name, "read" + name} {
+			if err := g.declare(ident, "abi struct "+s.Name); err != nil {
+				return err
+			}
+		}
+		g.printf("\n/** The abi struct %s. */\nexport interface %s {\n", s.Name, name)
+		for _, pair := range g.fields(s) {
+			g.printf("  %s: %s;\n", tsKey(pair.Key), g.tsType(pair.Value))
+		}
+		g.printf("}\n")
+	}
+
+	g.buf.WriteString(tsRuntime)
+
+	for _, v := range g.abi.Variants {
+		g.generateVariant(&v)
+	}
+	for i := range g.abi.Structs {
+		g.generateStruct(&g.abi.Structs[i])
+	}
+
+	type action struct{ key, name string }
+	var actions []action
+	for _, a := range g.abi.Actions {
+		typ := g.resolve(a.Type)
+		if g.getStruct(typ) == nil {
+			return fmt.Errorf("abigen: action %s: type %s is not a struct", a.ActionName, a.Type)
+		}
+
+		name := goName(a.ActionName)
+		for _, ident := range []string{"encode" + name, "decode" + name} {
+			if err := g.declare(ident, "action "+a.ActionName); err != nil {
+				return err
+			}
+		}
+		actions = append(actions, action{tsKey(a.ActionName), name})
+
+		g.printf(`
+/** Encodes the parameters of action %[1]s. */
+export function encode%[2]s(v: %[3]s): Uint8Array {
+  const w: Writer = { bytes: [] };
+  write%[3]s(w, v);
+  return Uint8Array.from(w.bytes);
+}
+
+/** Decodes the parameters of action %[1]s, trailing bytes are an error. */
+export function decode%[2]s(data: Uint8Array): %[3]s {
+  const r: Reader = { data, pos: 0 };
+  const v = read%[3]s(r);
+  expectEnd(r);
+  return v;
+}
+`, a.ActionName, name, goName(typ))
+	}
+
+	g.printf("\n/** The encode and decode functions of each action. */\nexport const actions = {\n")
+	for _, a := range actions {
+		g.printf("  %s: { encode: encode%s, decode: decode%s },\n", a.key, a.name, a.name)
+	}
+	g.printf("};\n")
+	return nil
+}
+
+func (g *tsGen) generateVariant(v *msgpack.ABIVariant) {
+	name := goName(v.Name)
+
+	g.printf("\nexport function write%s(w: Writer, v: %s): void {\n  packArraySize(w, 2);\n  switch (v.type) {\n", name, name)
+	for i, typ := range v.Types {
+		g.printf("    case %s:\n      packUint8(w, %d);\n", jsString(typ), i)
+		for _, line := range g.writeStmts(typ, "v.value", 0) {
+			g.printf("      %s\n", line)
+		}
+		g.printf("      return;\n")
+	}
+	g.printf("  }\n  throw new Error(%s);\n}\n", jsString("type is not in variant "+v.Name))
+
+	g.printf("\nexport function read%s(r: Reader): %s {\n  expectSize(r, 2, %s);\n  const index = unpackUint8(r);\n  switch (index) {\n", name, name, jsString("variant "+v.Name))
+	for i, typ := range v.Types {
+		g.printf("    case %d:\n      return { type: %s, value: %s };\n", i, jsString(typ), g.readExpr(typ))
+	}
+	g.printf("  }\n  throw new Error(`variant %s: type index ${index} out of range`);\n}\n", tsTemplateEscape(v.Name))
+}
+
+func (g *tsGen) generateStruct(s *msgpack.ABIStruct) {
+	name := goName(s.Name)
+	fields := g.fields(s)
+
+	g.printf("\nexport function write%s(w: Writer, v: %s): void {\n  packArraySize(w, %d);\n", name, name, len(fields))
+	for _, pair := range fields {
+		for _, line := range g.writeStmts(pair.Value, "v"+tsAccess(pair.Key), 0) {
+			g.printf("  %s\n", line)
+		}
+	}
+	g.printf("}\n")
+
+	g.printf("\nexport function read%s(r: Reader): %s {\n  expectSize(r, %d, %s);\n  return {\n", name, name, len(fields), jsString(s.Name))
+	for _, pair := range fields {
+		g.printf("    %s: %s,\n", tsKey(pair.Key), g.readExpr(pair.Value))
+	}
+	g.printf("  };\n}\n")
+}
+
+//tsType maps an abi type to its TypeScript type
+func (g *tsGen) tsType(typ string) string {
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		elem := g.tsType(strings.TrimSuffix(typ, "[]"))
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case strings.HasSuffix(typ, "?"):
+		return g.tsType(strings.TrimSuffix(typ, "?")) + " | null"
+	}
+	switch typ {
+	case "string":
+		return "string"
+	case "uint8", "uint16", "uint32":
+		return "number"
+	case "uint64":
+		return "bigint"
+	case "bytes":
+		return "Uint8Array"
+	}
+	return goName(typ)
+}
+
+//writeStmts returns the statements writing expr of abi type typ to w
+func (g *tsGen) writeStmts(typ string, expr string, depth int) []string {
+	typ = g.resolve(typ)
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		elem := fmt.Sprintf("e%d", depth)
+		lines := []string{
+			fmt.Sprintf("packArraySize(w, %s.length);", expr),
+			fmt.Sprintf("for (const %s of %s) {", elem, expr),
+		}
+		for _, line := range g.writeStmts(strings.TrimSuffix(typ, "[]"), elem, depth+1) {
+			lines = append(lines, "  "+line)
+		}
+		return append(lines, "}")
+	case strings.HasSuffix(typ, "?"):
+		lines := []string{
+			fmt.Sprintf("if (%s === null) {", expr),
+			"  packNil(w);",
+			"} else {",
+		}
+		for _, line := range g.writeStmts(strings.TrimSuffix(typ, "?"), expr, depth+1) {
+			lines = append(lines, "  "+line)
+		}
+		return append(lines, "}")
+	}
+
+	switch typ {
+	case "string":
+		return []string{fmt.Sprintf("packStr16(w, %s);", expr)}
+	case "uint8", "uint16", "uint32", "uint64":
+		return []string{fmt.Sprintf("pack%s(w, %s);", "U"+typ[1:], expr)}
+	case "bytes":
+		return []string{fmt.Sprintf("packBin16(w, %s);", expr)}
+	}
+	return []string{fmt.Sprintf("write%s(w, %s);", goName(typ), expr)}
+}
+
+//readExpr returns the expression reading a value of abi type typ from r
+func (g *tsGen) readExpr(typ string) string {
+	typ = g.resolve(typ)
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		return fmt.Sprintf("unpackArray(r, (r) => %s)", g.readExpr(strings.TrimSuffix(typ, "[]")))
+	case strings.HasSuffix(typ, "?"):
+		return fmt.Sprintf("unpackOptional(r, (r) => %s)", g.readExpr(strings.TrimSuffix(typ, "?")))
+	}
+
+	switch typ {
+	case "string":
+		return "unpackStr16(r)"
+	case "uint8", "uint16", "uint32", "uint64":
+		return fmt.Sprintf("unpack%s(r)", "U"+typ[1:])
+	case "bytes":
+		return "unpackBin16(r)"
+	}
+	return fmt.Sprintf("read%s(r)", goName(typ))
+}
+
+var tsIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+//tsKey is name as an object key
+func tsKey(name string) string {
+	if tsIdent.MatchString(name) {
+		return name
+	}
+	return jsString(name)
+}
+
+//tsAccess is the property access of name
+func tsAccess(name string) string {
+	if tsIdent.MatchString(name) {
+		return "." + name
+	}
+	return "[" + jsString(name) + "]"
+}
+
+func tsTemplateEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`", "$", "\\$").Replace(s)
+}
+
+//GenerateTypeScriptVectors emits a TypeScript module exporting golden
+//vectors: a zero and a max sample of each action with its hex encoding by
+//the Go encoder, for checking a module from GenerateTypeScript against.
+func GenerateTypeScriptVectors(abi *msgpack.ABI) ([]byte, error) {
+	vectors, err := sampleVectors(abi)
+	if err != nil {
+		return nil, err
+	}
+
+	x := abiIndex{abi}
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by msgpack gen-ts. DO NOT EDIT.\n\n")
+	buf.WriteString("/** Sample parameters of each action and their encoding by the Go encoder. */\n")
+	buf.WriteString("export const vectors = [\n")
+	for _, v := range vectors {
+		fmt.Fprintf(&buf, "  {\n    action: %s,\n    name: %s,\n    hex: %s,\n    value: %s,\n  },\n",
+			jsString(v.action), jsString(v.name), jsString(fmt.Sprintf("%x", v.data)), x.tsValue(v.typ, v.value))
+	}
+	buf.WriteString("];\n")
+	return buf.Bytes(), nil
+}
+
+//tsValue renders a sample value of abi type typ as a TypeScript literal
+func (x abiIndex) tsValue(typ string, value interface{}) string {
+	typ = x.resolve(typ)
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		var elems []string
+		for _, e := range value.([]interface{}) {
+			elems = append(elems, x.tsValue(strings.TrimSuffix(typ, "[]"), e))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case strings.HasSuffix(typ, "?"):
+		if value == nil {
+			return "null"
+		}
+		return x.tsValue(strings.TrimSuffix(typ, "?"), value)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return jsString(v)
+	case uint8, uint16, uint32:
+		return fmt.Sprint(v)
+	case uint64:
+		return fmt.Sprintf("%dn", v)
+	case []byte:
+		var elems []string
+		for _, b := range v {
+			elems = append(elems, fmt.Sprint(b))
+		}
+		return "new Uint8Array([" + strings.Join(elems, ", ") + "])"
+	case msgpack.Variant:
+		return fmt.Sprintf("{ type: %s, value: %s }", jsString(v.Type), x.tsValue(v.Type, v.Value))
+	case map[string]interface{}:
+		var fields []string
+		for _, pair := range x.fields(x.getStruct(typ)) {
+			fields = append(fields, tsKey(pair.Key)+": "+x.tsValue(pair.Value, v[pair.Key]))
+		}
+		if len(fields) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(fields, ", ") + " }"
+	}
+	panic(fmt.Sprintf("abigen: unexpected sample %T", value))
+}
+
+const tsRuntime = `
+export interface Writer {
+  bytes: number[];
+}
+
+export interface Reader {
+  data: Uint8Array;
+  pos: number;
+}
+
+const utf8Encoder = new TextEncoder();
+const utf8Decoder = new TextDecoder("utf-8", { fatal: true });
+
+function checkUint(n: number, max: number, name: string): void {
+  if (!Number.isInteger(n) || n < 0 || n > max) {
+    throw new RangeError(` + "`${n} is not a valid ${name}`" + `);
+  }
+}
+
+function packHeader(w: Writer, code: number, length: number): void {
+  checkUint(length, 0xffff, "length");
+  w.bytes.push(code, length >> 8, length & 0xff);
+}
+
+export function packNil(w: Writer): void {
+  w.bytes.push(0xc0);
+}
+
+export function packArraySize(w: Writer, length: number): void {
+  packHeader(w, 0xdc, length);
+}
+
+export function packStr16(w: Writer, s: string): void {
+  const b = utf8Encoder.encode(s);
+  packHeader(w, 0xda, b.length);
+  for (const c of b) {
+    w.bytes.push(c);
+  }
+}
+
+export function packBin16(w: Writer, b: Uint8Array): void {
+  packHeader(w, 0xc5, b.length);
+  for (const c of b) {
+    w.bytes.push(c);
+  }
+}
+
+export function packUint8(w: Writer, n: number): void {
+  checkUint(n, 0xff, "uint8");
+  w.bytes.push(0xcc, n);
+}
+
+export function packUint16(w: Writer, n: number): void {
+  checkUint(n, 0xffff, "uint16");
+  w.bytes.push(0xcd, n >> 8, n & 0xff);
+}
+
+export function packUint32(w: Writer, n: number): void {
+  checkUint(n, 0xffffffff, "uint32");
+  w.bytes.push(0xce, (n >>> 24) & 0xff, (n >>> 16) & 0xff, (n >>> 8) & 0xff, n & 0xff);
+}
+
+export function packUint64(w: Writer, n: bigint): void {
+  if (typeof n !== "bigint" || n < 0n || n > 0xffffffffffffffffn) {
+    throw new RangeError(` + "`${n} is not a valid uint64`" + `);
+  }
+  w.bytes.push(0xcf);
+  for (let shift = 56n; shift >= 0n; shift -= 8n) {
+    w.bytes.push(Number((n >> shift) & 0xffn));
+  }
+}
+
+function nextByte(r: Reader): number {
+  if (r.pos >= r.data.length) {
+    throw new Error("unexpected end of data");
+  }
+  return r.data[r.pos++];
+}
+
+function nextUint(r: Reader, size: number): number {
+  let n = 0;
+  for (let i = 0; i < size; i++) {
+    n = n * 256 + nextByte(r);
+  }
+  return n;
+}
+
+function nextBytes(r: Reader, length: number): Uint8Array {
+  if (r.pos + length > r.data.length) {
+    throw new Error("unexpected end of data");
+  }
+  const b = r.data.slice(r.pos, r.pos + length);
+  r.pos += length;
+  return b;
+}
+
+function expectHeader(r: Reader, code: number, name: string): void {
+  const c = nextByte(r);
+  if (c !== code) {
+    throw new Error(` + "`Not ${name}: 0x${c.toString(16)}`" + `);
+  }
+}
+
+function expectSize(r: Reader, size: number, name: string): void {
+  const n = unpackArraySize(r);
+  if (n !== size) {
+    throw new Error(` + "`${name}: fields number mismatch! abi: ${size}, data: ${n}`" + `);
+  }
+}
+
+function expectEnd(r: Reader): void {
+  if (r.pos !== r.data.length) {
+    throw new Error(` + "`${r.data.length - r.pos} trailing bytes`" + `);
+  }
+}
+
+export function unpackArraySize(r: Reader): number {
+  expectHeader(r, 0xdc, "array16");
+  return nextUint(r, 2);
+}
+
+export function unpackStr16(r: Reader): string {
+  expectHeader(r, 0xda, "str16");
+  return utf8Decoder.decode(nextBytes(r, nextUint(r, 2)));
+}
+
+export function unpackBin16(r: Reader): Uint8Array {
+  expectHeader(r, 0xc5, "bin16");
+  return nextBytes(r, nextUint(r, 2));
+}
+
+export function unpackUint8(r: Reader): number {
+  expectHeader(r, 0xcc, "uint8");
+  return nextUint(r, 1);
+}
+
+export function unpackUint16(r: Reader): number {
+  expectHeader(r, 0xcd, "uint16");
+  return nextUint(r, 2);
+}
+
+export function unpackUint32(r: Reader): number {
+  expectHeader(r, 0xce, "uint32");
+  return nextUint(r, 4);
+}
+
+export function unpackUint64(r: Reader): bigint {
+  expectHeader(r, 0xcf, "uint64");
+  let n = 0n;
+  for (let i = 0; i < 8; i++) {
+    n = (n << 8n) | BigInt(nextByte(r));
+  }
+  return n;
+}
+
+export function unpackArray<T>(r: Reader, f: (r: Reader) => T): T[] {
+  const length = unpackArraySize(r);
+  const a: T[] = [];
+  for (let i = 0; i < length; i++) {
+    a.push(f(r));
+  }
+  return a;
+}
+
+export function unpackOptional<T>(r: Reader, f: (r: Reader) => T): T | null {
+  if (r.pos < r.data.length && r.data[r.pos] === 0xc0) {
+    r.pos++;
+    return null;
+  }
+  return f(r);
+}
+`
@@ -0,0 +1,188 @@
+package abigen
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+func TestGenerateTypeScriptGolden(t *testing.T) {
+	abi, err := msgpack.LoadAbiFile("testdata/gentest.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for file, generate := range map[string]func(*msgpack.ABI) ([]byte, error){
+		"testdata/gentest.ts":         GenerateTypeScript,
+		"testdata/gentest.vectors.ts": GenerateTypeScriptVectors,
+	} {
+		src, err := generate(abi)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(src) != string(golden) {
+			t.Fatalf("output differs from %s, run go generate ./abigen/...:\n%s", file, src)
+		}
+	}
+}
+
+// runVectors checks the gentest codec against the golden vectors in both
+// directions, plus its range and framing errors. %[1]s is the extension the
+// modules are imported with.
+const runVectors = `import assert from "node:assert";
+import * as codec from "./gentest.%[1]s";
+import { vectors } from "./gentest.vectors.%[1]s";
+
+const hex = (b) => Buffer.from(b).toString("hex");
+const bytes = (h) => Uint8Array.from(Buffer.from(h, "hex"));
+for (const v of vectors) {
+  const a = codec.actions[v.action];
+  assert.strictEqual(hex(a.encode(v.value)), v.hex, v.action + "/" + v.name + " encode");
+  assert.deepStrictEqual(a.decode(bytes(v.hex)), v.value, v.action + "/" + v.name + " decode");
+}
+assert.throws(() => codec.encodeBatchtransfer({ ...vectors[0].value, flag: 256 }));
+assert.throws(() => codec.decodePropose(bytes(vectors[2].hex + "00")));
+assert.throws(() => codec.decodePropose(bytes(vectors[2].hex.slice(0, 10))));
+console.log("ok " + vectors.length + " vectors");
+`
+
+// TestGenerateTypeScriptVectorsRun runs the golden TypeScript modules with
+// node, stripping the types with node itself where it can (node 22.6 and
+// later) and compiling them with tsc otherwise. Without either the test is
+// skipped, except in CI where it fails.
+func TestGenerateTypeScriptVectorsRun(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		skipOutsideCI(t, "node not found")
+	}
+
+	dir, err := ioutil.TempDir("", "abigen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"type": "module"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{filepath.Join(dir, "run.js")}
+	ext := "js"
+	if exec.Command(node, "--experimental-strip-types", "-e", "").Run() == nil {
+		for _, file := range []string{"gentest.ts", "gentest.vectors.ts"} {
+			src, err := ioutil.ReadFile(filepath.Join("testdata", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = ioutil.WriteFile(filepath.Join(dir, file), src, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		args = append([]string{"--experimental-strip-types", "--no-warnings"}, args...)
+		ext = "ts"
+	} else {
+		tsc, err := exec.LookPath("tsc")
+		if err != nil {
+			skipOutsideCI(t, "node can not strip types and tsc not found")
+		}
+		out, err := exec.Command(tsc, "--strict", "--target", "es2020", "--module", "es2020", "--outDir", dir,
+			"testdata/gentest.ts", "testdata/gentest.vectors.ts").CombinedOutput()
+		if err != nil {
+			t.Fatalf("tsc: %v\n%s", err, out)
+		}
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "run.js"), []byte(fmt.Sprintf(runVectors, ext)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(node, args...).CombinedOutput()
+	if err != nil || !strings.HasPrefix(string(out), "ok ") {
+		t.Fatalf("vectors: %v\n%s", err, out)
+	}
+}
+
+// skipOutsideCI skips t, or fails it when the CI environment variable is set
+// so that a CI run without the toolchain does not pass silently
+func skipOutsideCI(t *testing.T, reason string) {
+	t.Helper()
+	if os.Getenv("CI") != "" {
+		t.Fatal(reason)
+	}
+	t.Skip(reason)
+}
+
+func TestSampleVectors(t *testing.T) {
+	abi, err := msgpack.LoadAbiFile("testdata/gentest.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := msgpack.CompileAbi(abi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vectors, err := sampleVectors(abi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2*len(abi.Actions) {
+		t.Fatalf("sampleVectors: %d vectors", len(vectors))
+	}
+	for _, v := range vectors {
+		fm, err := c.Decode(v.action, v.data)
+		if err != nil {
+			t.Fatalf("%s/%s: %v", v.action, v.name, err)
+		}
+		b, err := c.Encode(v.action, fm)
+		if err != nil || hex.EncodeToString(b) != hex.EncodeToString(v.data) {
+			t.Fatalf("%s/%s: re-encoded %x %v", v.action, v.name, b, err)
+		}
+	}
+
+	js, _ := json.Marshal(vectors[0].value)
+	if string(js) != `{"data":"","extra":null,"flag":0,"from":"","items":[],"kind":0,"memo":null,"to":[],"values":[]}` {
+		t.Fatalf("zero sample: %s", js)
+	}
+}
+
+func TestGenerateTypeScriptNames(t *testing.T) {
+	abi, err := msgpack.ParseAbi([]byte(`{
+		"structs": [{"name": "item", "base": "", "fields": {"user-name": "string", "ids": "uint16?[]"}}],
+		"actions": [{"action_name": "add.item", "type": "item"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := GenerateTypeScript(abi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`  "user-name": string;`,
+		`  ids: (number | null)[];`,
+		`  packStr16(w, v["user-name"]);`,
+		`    ids: unpackArray(r, (r) => unpackOptional(r, (r) => unpackUint16(r))),`,
+		`export function encodeAddItem(v: Item): Uint8Array {`,
+		`  "add.item": { encode: encodeAddItem, decode: decodeAddItem },`,
+	} {
+		if !strings.Contains(string(src), expect) {
+			t.Fatalf("GenerateTypeScript: missing %q in\n%s", expect, src)
+		}
+	}
+
+	abi.Structs = append(abi.Structs, msgpack.ABIStruct{Name: "writer", Fields: msgpack.New()})
+	if _, err = GenerateTypeScript(abi); err == nil || !strings.Contains(err.Error(), "collides") {
+		t.Fatalf("GenerateTypeScript: expected collision with runtime, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package abigen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+//vector is a sample action payload encoded by the Go encoder, for checking
+//generated codecs against it
+type vector struct {
+	action string
+	name   string
+	typ    string // resolved action struct
+	value  interface{}
+	data   []byte
+}
+
+//sampleKind selects the sample values of a vector
+type sampleKind int
+
+const (
+	//sampleZero has empty strings, bytes and arrays, zero numbers, absent
+	//optionals and the first type of each variant
+	sampleZero sampleKind = iota
+	//sampleMax has non ASCII strings, maximum numbers, arrays of two
+	//elements, present optionals and the last type of each variant
+	sampleMax
+)
+
+var sampleNames = map[sampleKind]string{sampleZero: "zero", sampleMax: "max"}
+
+//maxSampleDepth bounds the nesting of sample values, so that recursive
+//structs end in an empty array or an absent optional
+const maxSampleDepth = 4
+
+//sampleVectors builds and encodes the zero and max sample of every action
+func sampleVectors(abi *msgpack.ABI) ([]vector, error) {
+	c, err := msgpack.CompileAbi(abi)
+	if err != nil {
+		return nil, err
+	}
+
+	x := abiIndex{abi}
+	var vectors []vector
+	for _, a := range abi.Actions {
+		typ := x.resolve(a.Type)
+		for _, kind := range []sampleKind{sampleZero, sampleMax} {
+			value, err := x.sample(typ, kind, 0)
+			if err != nil {
+				return nil, fmt.Errorf("abigen: action %s: %v", a.ActionName, err)
+			}
+			data, err := c.Encode(a.ActionName, value)
+			if err != nil {
+				return nil, fmt.Errorf("abigen: action %s: %v", a.ActionName, err)
+			}
+			vectors = append(vectors, vector{action: a.ActionName, name: sampleNames[kind], typ: typ, value: value, data: data})
+		}
+	}
+	return vectors, nil
+}
+
+//sample returns a value of abi type typ, in the form accepted by
+//msgpack.CompiledAbi.Encode
+func (x abiIndex) sample(typ string, kind sampleKind, depth int) (interface{}, error) {
+	if depth > 4*maxSampleDepth {
+		return nil, fmt.Errorf("type %s nests too deep", typ)
+	}
+
+	typ = x.resolve(typ)
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		vals := []interface{}{}
+		if kind == sampleMax && depth < maxSampleDepth {
+			for i := 0; i < 2; i++ {
+				val, err := x.sample(strings.TrimSuffix(typ, "[]"), kind, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				vals = append(vals, val)
+			}
+		}
+		return vals, nil
+	case strings.HasSuffix(typ, "?"):
+		if kind == sampleZero || depth >= maxSampleDepth {
+			return nil, nil
+		}
+		return x.sample(strings.TrimSuffix(typ, "?"), kind, depth+1)
+	}
+
+	max := kind == sampleMax
+	switch typ {
+	case "string":
+		if max {
+			return "héllo, 世界 \"\\", nil
+		}
+		return "", nil
+	case "uint8":
+		if max {
+			return uint8(0xff), nil
+		}
+		return uint8(0), nil
+	case "uint16":
+		if max {
+			return uint16(0xffff), nil
+		}
+		return uint16(0), nil
+	case "uint32":
+		if max {
+			return uint32(0xffffffff), nil
+		}
+		return uint32(0), nil
+	case "uint64":
+		if max {
+			return uint64(0xffffffffffffffff), nil
+		}
+		return uint64(0), nil
+	case "bytes":
+		if max {
+			return []byte{0x00, 0x7f, 0xff}, nil
+		}
+		return []byte{}, nil
+	}
+
+	if v := x.getVariant(typ); v != nil {
+		i := 0
+		if max {
+			i = len(v.Types) - 1
+		}
+		val, err := x.sample(v.Types[i], kind, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return msgpack.Variant{Type: v.Types[i], Value: val}, nil
+	}
+
+	s := x.getStruct(typ)
+	if s == nil {
+		return nil, fmt.Errorf("undefined type %s", typ)
+	}
+	fields := map[string]interface{}{}
+	for _, pair := range x.fields(s) {
+		val, err := x.sample(pair.Value, kind, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		fields[pair.Key] = val
+	}
+	return fields, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bottos-project/msgpack-go/abigen"
+)
+
+// actionFlags collects repeated -action name=Type flags
+type actionFlags map[string]string
+
+func (a actionFlags) String() string {
+	var pairs []string
+	for name, typ := range a {
+		pairs = append(pairs, name+"="+typ)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (a actionFlags) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 || i == len(s)-1 {
+		return fmt.Errorf("want action=GoType, got %q", s)
+	}
+	a[s[:i]] = s[i+1:]
+	return nil
+}
+
+func runAbigenFromGo(args []string) error {
+	fs := flag.NewFlagSet("abigen-from-go", flag.ContinueOnError)
+	actions := actionFlags{}
+	fs.Var(actions, "action", "action `name=GoType`, GoType may be qualified as pkg.Type, may be repeated")
+	out := fs.String("o", "", "write the abi to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: msgpack abigen-from-go -action name=GoType [-action ...] [-o file] file.go|dir ...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(actions) == 0 || fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("need at least one -action and one source")
+	}
+
+	abi, err := abigen.FromGoFiles(fs.Args(), actions)
+	if err != nil {
+		return err
+	}
+	js, err := json.MarshalIndent(abi, "", "    ")
+	if err != nil {
+		return err
+	}
+	js = append(js, '\n')
+
+	if *out == "" {
+		_, err = os.Stdout.Write(js)
+		return err
+	}
+	return ioutil.WriteFile(*out, js, 0644)
+}
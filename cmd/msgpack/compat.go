@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+// exitBreaking is the exit status of abi-compat when a change is breaking,
+// apart from the status 1 of other failures such as an unreadable abi
+const exitBreaking = 3
+
+// breakingError is the error of abi-compat for n breaking changes
+type breakingError int
+
+func (n breakingError) Error() string {
+	return fmt.Sprintf("%d breaking changes", int(n))
+}
+
+func (n breakingError) ExitCode() int {
+	return exitBreaking
+}
+
+func runAbiCompat(args []string) error {
+	fs := flag.NewFlagSet("abi-compat", flag.ContinueOnError)
+	quiet := fs.Bool("q", false, "only print breaking changes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: msgpack abi-compat [-q] old.abi new.abi")
+		fmt.Fprintln(fs.Output(), "exit status is 0 without breaking changes, 3 with breaking changes and 1 on errors")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("need the old and the new abi")
+	}
+
+	oldAbi, err := msgpack.LoadAbiFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newAbi, err := msgpack.LoadAbiFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	return reportChanges(os.Stdout, msgpack.CompareAbi(oldAbi, newAbi), *quiet)
+}
+
+// reportChanges prints changes and fails if any of them is breaking
+func reportChanges(w io.Writer, changes []msgpack.Change, quiet bool) error {
+	breaking := 0
+	for _, c := range changes {
+		if c.Breaking {
+			breaking++
+		} else if quiet {
+			continue
+		}
+		fmt.Fprintln(w, c)
+	}
+	if breaking > 0 {
+		return breakingError(breaking)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+func TestReportChanges(t *testing.T) {
+	changes := []msgpack.Change{
+		{Kind: msgpack.ActionAdded, Path: "actions.burn", New: "burn"},
+		{Kind: msgpack.FieldRemoved, Breaking: true, Path: "structs.transfer.fields.value", Old: "uint64"},
+	}
+
+	var out bytes.Buffer
+	err := reportChanges(&out, changes, false)
+	if err == nil || err.Error() != "1 breaking changes" || err.(exitCoder).ExitCode() != exitBreaking {
+		t.Fatalf("reportChanges: %v", err)
+	}
+	expect := "compatible: actions.burn: action added: burn\nbreaking: structs.transfer.fields.value: field removed: uint64\n"
+	if out.String() != expect {
+		t.Fatalf("reportChanges:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err = reportChanges(&out, changes[:1], true); err != nil || out.Len() != 0 {
+		t.Fatalf("reportChanges -q: %v %q", err, out.String())
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+func runDump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	format := fs.String("in", "auto", "input format: auto, hex, base64 or raw")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: msgpack dump [-in format] [file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var input []byte
+	var err error
+	switch fs.NArg() {
+	case 0:
+		input, err = ioutil.ReadAll(os.Stdin)
+	case 1:
+		input, err = ioutil.ReadFile(fs.Arg(0))
+	default:
+		fs.Usage()
+		return fmt.Errorf("too many arguments")
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := decodeInput(input, *format)
+	if err != nil {
+		return err
+	}
+
+	return dump(os.Stdout, data)
+}
+
+// decodeInput turns hex, base64 or raw input into bytes. In auto mode text
+// that is valid hex is taken as hex, then base64 is tried, then raw.
+func decodeInput(input []byte, format string) ([]byte, error) {
+	text := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, string(input))
+	text = strings.TrimPrefix(text, "0x")
+
+	switch format {
+	case "hex":
+		return hex.DecodeString(text)
+	case "base64":
+		return base64.StdEncoding.DecodeString(text)
+	case "raw":
+		return input, nil
+	case "auto":
+		if data, err := hex.DecodeString(text); err == nil {
+			return data, nil
+		}
+		if data, err := base64.StdEncoding.DecodeString(text); err == nil {
+			return data, nil
+		}
+		return input, nil
+	}
+	return nil, fmt.Errorf("unknown input format %q", format)
+}
+
+// dumper prints one line per value: offset, type marker, length or payload
+// bytes, type name and value. Elements of arrays and maps are indented.
+type dumper struct {
+	w    io.Writer
+	data []byte
+	off  int
+}
+
+func dump(w io.Writer, data []byte) error {
+	d := &dumper{w: w, data: data}
+	for d.off < len(d.data) {
+		if err := d.value(0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dumper) take(n int) ([]byte, error) {
+	if d.off+n > len(d.data) {
+		return nil, fmt.Errorf("offset 0x%04x: need %d bytes, %d left", d.off, n, len(d.data)-d.off)
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func beInt(b []byte) int64 {
+	v := beUint(b)
+	shift := uint(64 - 8*len(b))
+	return int64(v<<shift) >> shift
+}
+
+func (d *dumper) line(depth int, start int, marker byte, extra []byte, desc string) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s0x%04x %02x", strings.Repeat("  ", depth), start, marker)
+	if len(extra) > 0 {
+		fmt.Fprintf(&buf, " %x", extra)
+	}
+	fmt.Fprintf(&buf, " %s\n", desc)
+	d.w.Write(buf.Bytes())
+}
+
+// sized describes the header of variable length values
+var sized = map[byte]struct {
+	name string
+	size int
+}{
+	msgpack.BIN8: {"bin8", 1}, msgpack.BIN16: {"bin16", 2}, msgpack.BIN32: {"bin32", 4},
+	msgpack.EXT8: {"ext8", 1}, msgpack.EXT16: {"ext16", 2}, msgpack.EXT32: {"ext32", 4},
+	msgpack.STR8: {"str8", 1}, msgpack.STR16: {"str16", 2}, msgpack.STR32: {"str32", 4},
+	msgpack.ARRAY16: {"array16", 2}, msgpack.ARRAY32: {"array32", 4},
+	msgpack.MAP16: {"map16", 2}, msgpack.MAP32: {"map32", 4},
+}
+
+// fixed describes the header of fixed length values
+var fixed = map[byte]struct {
+	name string
+	size int
+}{
+	msgpack.UINT8: {"uint8", 1}, msgpack.UINT16: {"uint16", 2}, msgpack.UINT32: {"uint32", 4}, msgpack.UINT64: {"uint64", 8},
+	msgpack.INT8: {"int8", 1}, msgpack.INT16: {"int16", 2}, msgpack.INT32: {"int32", 4}, msgpack.INT64: {"int64", 8},
+	msgpack.FLOAT32: {"float32", 4}, msgpack.FLOAT64: {"float64", 8},
+	msgpack.FIXEXT1: {"fixext1", 2}, msgpack.FIXEXT2: {"fixext2", 3}, msgpack.FIXEXT4: {"fixext4", 5},
+	msgpack.FIXEXT8: {"fixext8", 9}, msgpack.FIXEXT16: {"fixext16", 17},
+}
+
+func (d *dumper) value(depth int) error {
+	start := d.off
+	b, err := d.take(1)
+	if err != nil {
+		return err
+	}
+	c := b[0]
+
+	switch {
+	case c <= msgpack.POSFIXNUMMAX:
+		d.line(depth, start, c, nil, fmt.Sprintf("fixint %d", c))
+		return nil
+	case c >= msgpack.NEGFIXNUM:
+		d.line(depth, start, c, nil, fmt.Sprintf("fixint %d", int8(c)))
+		return nil
+	case c <= msgpack.FIXMAPMAX:
+		d.line(depth, start, c, nil, fmt.Sprintf("fixmap(%d)", c&msgpack.FIRSTBYTEMASK))
+		return d.elements(depth, 2*int(c&msgpack.FIRSTBYTEMASK))
+	case c <= msgpack.FIXARRAYMAX:
+		d.line(depth, start, c, nil, fmt.Sprintf("fixarray(%d)", c&msgpack.FIRSTBYTEMASK))
+		return d.elements(depth, int(c&msgpack.FIRSTBYTEMASK))
+	case c <= msgpack.FIXRAWMAX:
+		s, err := d.take(int(c & msgpack.FIXRAWMASK))
+		if err != nil {
+			return err
+		}
+		d.line(depth, start, c, nil, fmt.Sprintf("fixstr %q", s))
+		return nil
+	case c == msgpack.NIL:
+		d.line(depth, start, c, nil, "nil")
+		return nil
+	case c == msgpack.FALSE:
+		d.line(depth, start, c, nil, "false")
+		return nil
+	case c == msgpack.TRUE:
+		d.line(depth, start, c, nil, "true")
+		return nil
+	}
+
+	if f, ok := fixed[c]; ok {
+		v, err := d.take(f.size)
+		if err != nil {
+			return err
+		}
+		desc := f.name
+		switch {
+		case c >= msgpack.UINT8 && c <= msgpack.UINT64:
+			desc += fmt.Sprintf(" %d", beUint(v))
+		case c >= msgpack.INT8 && c <= msgpack.INT64:
+			desc += fmt.Sprintf(" %d", beInt(v))
+		case c == msgpack.FLOAT32:
+			desc += fmt.Sprintf(" %g", math.Float32frombits(uint32(beUint(v))))
+		case c == msgpack.FLOAT64:
+			desc += fmt.Sprintf(" %g", math.Float64frombits(beUint(v)))
+		case c >= msgpack.FIXEXT1:
+			desc += fmt.Sprintf(" type=%d", int8(v[0]))
+		}
+		d.line(depth, start, c, v, desc)
+		return nil
+	}
+
+	s, ok := sized[c]
+	if !ok {
+		d.line(depth, start, c, nil, "invalid")
+		return fmt.Errorf("offset 0x%04x: invalid type 0x%02x", start, c)
+	}
+	lb, err := d.take(s.size)
+	if err != nil {
+		return err
+	}
+	n := int(beUint(lb))
+
+	switch {
+	case c >= msgpack.ARRAY16 && c <= msgpack.ARRAY32:
+		d.line(depth, start, c, lb, fmt.Sprintf("%s(%d)", s.name, n))
+		return d.elements(depth, n)
+	case c >= msgpack.MAP16:
+		d.line(depth, start, c, lb, fmt.Sprintf("%s(%d)", s.name, n))
+		return d.elements(depth, 2*n)
+	case c >= msgpack.EXT8 && c <= msgpack.EXT32:
+		n++ // ext type byte
+	}
+
+	payload, err := d.take(n)
+	if err != nil {
+		return err
+	}
+	switch {
+	case c >= msgpack.STR8:
+		d.line(depth, start, c, lb, fmt.Sprintf("%s %q", s.name, payload))
+	case c >= msgpack.EXT8:
+		d.line(depth, start, c, lb, fmt.Sprintf("%s type=%d %x", s.name, int8(payload[0]), payload[1:]))
+	default:
+		d.line(depth, start, c, lb, fmt.Sprintf("%s %x", s.name, payload))
+	}
+	return nil
+}
+
+func (d *dumper) elements(depth int, n int) error {
+	for i := 0; i < n; i++ {
+		if err := d.value(depth + 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
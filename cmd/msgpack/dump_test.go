@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	data, err := decodeInput([]byte("dc0003da00087465737475736572ce00000063\ndc0002da0003313233c5000201ff\n"), "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err = dump(&out, data); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `0x0000 dc 0003 array16(3)
+  0x0003 da 0008 str16 "testuser"
+  0x000e ce 00000063 uint32 99
+  0x0013 dc 0002 array16(2)
+    0x0016 da 0003 str16 "123"
+    0x001c c5 0002 bin16 01ff
+`
+	if out.String() != expect {
+		t.Fatalf("dump mismatch:\n%s", out.String())
+	}
+
+	if err = dump(&out, data[:10]); err == nil {
+		t.Fatal("expected error on truncated input")
+	}
+
+	// float32 1.5, float64 -0.25
+	out.Reset()
+	if err = dump(&out, []byte{0xca, 0x3f, 0xc0, 0, 0, 0xcb, 0xbf, 0xd0, 0, 0, 0, 0, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	expect = `0x0000 ca 3fc00000 float32 1.5
+0x0005 cb bfd0000000000000 float64 -0.25
+`
+	if out.String() != expect {
+		t.Fatalf("dump floats mismatch:\n%s", out.String())
+	}
+}
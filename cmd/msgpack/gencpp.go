@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bottos-project/msgpack-go"
+	"github.com/bottos-project/msgpack-go/abigen"
+)
+
+func runGenCpp(args []string) error {
+	fs := flag.NewFlagSet("gen-cpp", flag.ContinueOnError)
+	abiFile := fs.String("abi", "", "read the abi from `file`")
+	ns := fs.String("ns", "abi", "C++ `namespace` of the generated code")
+	out := fs.String("o", "", "write the header to `file` instead of stdout")
+	vectors := fs.String("vectors", "", "also write a program checking the header against golden vectors to `file`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: msgpack gen-cpp -abi file [-ns namespace] [-o file] [-vectors file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *abiFile == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("need -abi")
+	}
+	if *vectors != "" && *out == "" {
+		return fmt.Errorf("-vectors needs -o to include the header")
+	}
+
+	abi, err := msgpack.LoadAbiFile(*abiFile)
+	if err != nil {
+		return err
+	}
+	src, err := abigen.GenerateCpp(abi, *ns)
+	if err != nil {
+		return err
+	}
+	if *vectors != "" {
+		header, err := filepath.Rel(filepath.Dir(*vectors), *out)
+		if err != nil {
+			return err
+		}
+		vsrc, err := abigen.GenerateCppVectors(abi, *ns, filepath.ToSlash(header))
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(*vectors, vsrc, 0644); err != nil {
+			return err
+		}
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(*out, src, 0644)
+}
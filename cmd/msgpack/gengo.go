@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bottos-project/msgpack-go"
+	"github.com/bottos-project/msgpack-go/abigen"
+)
+
+func runGenGo(args []string) error {
+	fs := flag.NewFlagSet("gen-go", flag.ContinueOnError)
+	abiFile := fs.String("abi", "", "read the abi from `file`")
+	pkg := fs.String("pkg", "", "package `name` of the generated code")
+	out := fs.String("o", "", "write the code to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: msgpack gen-go -abi file -pkg name [-o file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *abiFile == "" || *pkg == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("need -abi and -pkg")
+	}
+
+	abi, err := msgpack.LoadAbiFile(*abiFile)
+	if err != nil {
+		return err
+	}
+	src, err := abigen.GenerateGo(abi, *pkg)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(*out, src, 0644)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bottos-project/msgpack-go"
+	"github.com/bottos-project/msgpack-go/abigen"
+)
+
+func runGenTS(args []string) error {
+	fs := flag.NewFlagSet("gen-ts", flag.ContinueOnError)
+	abiFile := fs.String("abi", "", "read the abi from `file`")
+	out := fs.String("o", "", "write the module to `file` instead of stdout")
+	vectors := fs.String("vectors", "", "also write golden test vectors to `file`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: msgpack gen-ts -abi file [-o file] [-vectors file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *abiFile == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("need -abi")
+	}
+
+	abi, err := msgpack.LoadAbiFile(*abiFile)
+	if err != nil {
+		return err
+	}
+	src, err := abigen.GenerateTypeScript(abi)
+	if err != nil {
+		return err
+	}
+	if *vectors != "" {
+		vsrc, err := abigen.GenerateTypeScriptVectors(abi)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(*vectors, vsrc, 0644); err != nil {
+			return err
+		}
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(*out, src, 0644)
+}
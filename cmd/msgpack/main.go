@@ -0,0 +1,92 @@
+// Copyright 2017~2022 The Bottos Authors
+// This file is part of the Bottos Chain library.
+// Created by Rocket Core Team of Bottos.
+
+//This program is free software: you can distribute it and/or modify
+//it under the terms of the GNU General Public License as published by
+//the Free Software Foundation, either version 3 of the License, or
+//(at your option) any later version.
+
+//This program is distributed in the hope that it will be useful,
+//but WITHOUT ANY WARRANTY; without even the implied warranty of
+//MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//GNU General Public License for more details.
+
+//You should have received a copy of the GNU General Public License
+// along with bottos.  If not, see <http://www.gnu.org/licenses/>.
+
+// Command msgpack is a toolbox for the msgpack subset used by Bottos.
+//
+// Usage:
+//
+//	msgpack <command> [flags] [args]
+//
+// Commands:
+//
+//	dump            print an annotated tree of an encoded payload
+//	abigen-from-go  generate an abi from Go param structs
+//	gen-go          generate Go types and encode/decode helpers from an abi
+//	gen-ts          generate TypeScript types and codecs from an abi
+//	gen-cpp         generate C++ structs and pack/unpack functions from an abi
+//	abi-compat      list changes between two abi versions, fail on breaking ones
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// exitCoder is implemented by command errors that exit with a status other
+// than 1
+type exitCoder interface {
+	ExitCode() int
+}
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"dump", "print an annotated tree of an encoded payload", runDump},
+	{"abigen-from-go", "generate an abi from Go param structs", runAbigenFromGo},
+	{"gen-go", "generate Go types and encode/decode helpers from an abi", runGenGo},
+	{"gen-ts", "generate TypeScript types and codecs from an abi", runGenTS},
+	{"gen-cpp", "generate C++ structs and pack/unpack functions from an abi", runGenCpp},
+	{"abi-compat", "list changes between two abi versions, fail on breaking ones", runAbiCompat},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: msgpack <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", c.name, c.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+		if err := c.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "msgpack %s: %v\n", c.name, err)
+			if e, ok := err.(exitCoder); ok {
+				os.Exit(e.ExitCode())
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "msgpack: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,352 @@
+package msgpack
+
+import (
+	"fmt"
+	"strings"
+)
+
+//ChangeKind is the kind of a difference between two versions of an abi
+type ChangeKind int
+
+const (
+	//ActionAdded is a new action, compatible
+	ActionAdded ChangeKind = iota
+	//ActionRemoved is a removed action, breaking
+	ActionRemoved
+	//TableAdded is a new table, compatible
+	TableAdded
+	//TableRemoved is a removed table, breaking
+	TableRemoved
+	//TableKeyChanged is a changed index type or number of keys, breaking
+	TableKeyChanged
+	//FieldAppended is a new field after all remaining old fields, breaking as
+	//the array header carries the field count, so old payloads do not decode
+	//with the new abi nor new payloads with the old one. See MigratePayload.
+	FieldAppended
+	//FieldInserted is a new field before an old one, breaking
+	FieldInserted
+	//FieldRemoved is a removed field, breaking
+	FieldRemoved
+	//FieldReordered is a change in the order of the remaining fields, breaking
+	FieldReordered
+	//TypeChanged is a type with a different encoding, breaking
+	TypeChanged
+	//TypeRenamed is a type with another name but the same encoding, compatible
+	TypeRenamed
+	//VariantTypeAppended is a new last variant type, compatible
+	VariantTypeAppended
+	//VariantTypeRemoved is a removed variant type, breaking
+	VariantTypeRemoved
+	//AliasAdded is a new abi type, compatible
+	AliasAdded
+	//AliasRemoved is a removed abi type, compatible as aliases are not encoded
+	AliasRemoved
+	//AliasChanged is an abi type with a new target, breaking if the target
+	//encodes differently
+	AliasChanged
+)
+
+var changeKindNames = [...]string{
+	ActionAdded:         "action added",
+	ActionRemoved:       "action removed",
+	TableAdded:          "table added",
+	TableRemoved:        "table removed",
+	TableKeyChanged:     "table key changed",
+	FieldAppended:       "field appended",
+	FieldInserted:       "field inserted",
+	FieldRemoved:        "field removed",
+	FieldReordered:      "fields reordered",
+	TypeChanged:         "type changed",
+	TypeRenamed:         "type renamed",
+	VariantTypeAppended: "variant type appended",
+	VariantTypeRemoved:  "variant type removed",
+	AliasAdded:          "alias added",
+	AliasRemoved:        "alias removed",
+	AliasChanged:        "alias changed",
+}
+
+func (k ChangeKind) String() string {
+	if k < 0 || int(k) >= len(changeKindNames) {
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+	return changeKindNames[k]
+}
+
+//Change is one difference found by CompareAbi. Path locates it by name, e.g.
+//structs.transfer.fields.memo, and Old and New describe the two sides when
+//there is something to describe.
+type Change struct {
+	Kind     ChangeKind
+	Breaking bool
+	Path     string
+	Old      string
+	New      string
+}
+
+func (c Change) String() string {
+	s := "compatible"
+	if c.Breaking {
+		s = "breaking"
+	}
+	s += ": " + c.Path + ": " + c.Kind.String()
+	switch {
+	case c.Old != "" && c.New != "":
+		s += ": " + c.Old + " -> " + c.New
+	case c.Old != "":
+		s += ": " + c.Old
+	case c.New != "":
+		s += ": " + c.New
+	}
+	return s
+}
+
+//CompareAbi lists the differences between two versions of an abi that
+//matter to encoded payloads and table rows. Structs are arrays with a field
+//count, so any added or removed field is breaking; an appended field is told
+//apart from an inserted one as payloads can still be migrated mechanically.
+//Types are compared by encoding after aliases are resolved, so renaming is
+//compatible. Only types reachable from actions and tables are compared.
+func CompareAbi(old, new *ABI) []Change {
+	if old == nil {
+		old = &ABI{}
+	}
+	if new == nil {
+		new = &ABI{}
+	}
+
+	c := &abiComparer{old: old, new: new, visited: map[string]bool{}}
+	c.compareActions()
+	c.compareTables()
+	c.compareAliases()
+	return c.changes
+}
+
+type abiComparer struct {
+	old, new *ABI
+	changes  []Change
+	visited  map[string]bool // compared "old\x00new" struct and variant pairs
+}
+
+func (c *abiComparer) add(kind ChangeKind, breaking bool, path, old, new string) {
+	c.changes = append(c.changes, Change{Kind: kind, Breaking: breaking, Path: path, Old: old, New: new})
+}
+
+func (c *abiComparer) compareActions() {
+	newActions := map[string]ABIAction{}
+	for _, a := range c.new.Actions {
+		newActions[a.ActionName] = a
+	}
+	oldActions := map[string]bool{}
+	for _, a := range c.old.Actions {
+		oldActions[a.ActionName] = true
+		path := "actions." + a.ActionName
+		na, ok := newActions[a.ActionName]
+		if !ok {
+			c.add(ActionRemoved, true, path, a.Type, "")
+			continue
+		}
+		c.compareType(path, a.Type, na.Type)
+	}
+	for _, a := range c.new.Actions {
+		if !oldActions[a.ActionName] {
+			c.add(ActionAdded, false, "actions."+a.ActionName, "", a.Type)
+		}
+	}
+}
+
+func (c *abiComparer) compareTables() {
+	newTables := map[string]ABITable{}
+	for _, t := range c.new.Tables {
+		newTables[t.Name] = t
+	}
+	oldTables := map[string]bool{}
+	for _, t := range c.old.Tables {
+		oldTables[t.Name] = true
+		path := "tables." + t.Name
+		nt, ok := newTables[t.Name]
+		if !ok {
+			c.add(TableRemoved, true, path, t.Type, "")
+			continue
+		}
+
+		c.compareType(path+".type", t.Type, nt.Type)
+		if t.IndexType != nt.IndexType {
+			c.add(TableKeyChanged, true, path+".index_type", t.IndexType, nt.IndexType)
+		}
+		if len(t.KeyTypes) != len(nt.KeyTypes) {
+			c.add(TableKeyChanged, true, path+".key_types", strings.Join(t.KeyTypes, ","), strings.Join(nt.KeyTypes, ","))
+			continue
+		}
+		for i := range t.KeyTypes {
+			c.compareType(fmt.Sprintf("%s.key_types[%d]", path, i), t.KeyTypes[i], nt.KeyTypes[i])
+		}
+	}
+	for _, t := range c.new.Tables {
+		if !oldTables[t.Name] {
+			c.add(TableAdded, false, "tables."+t.Name, "", t.Type)
+		}
+	}
+}
+
+func (c *abiComparer) compareAliases() {
+	newTypes := map[string]string{}
+	for _, t := range c.new.Types {
+		newTypes[t.NewTypeName] = t.Type
+	}
+	oldTypes := map[string]bool{}
+	for _, t := range c.old.Types {
+		oldTypes[t.NewTypeName] = true
+		path := "types." + t.NewTypeName
+		target, ok := newTypes[t.NewTypeName]
+		if !ok {
+			c.add(AliasRemoved, false, path, t.Type, "")
+			continue
+		}
+		if target == t.Type {
+			continue
+		}
+
+		// the alias only breaks if its target encodes differently
+		sub := &abiComparer{old: c.old, new: c.new, visited: map[string]bool{}}
+		sub.compareType(path, t.Type, target)
+		breaking := false
+		for _, ch := range sub.changes {
+			breaking = breaking || ch.Breaking
+		}
+		c.add(AliasChanged, breaking, path, t.Type, target)
+	}
+	for _, t := range c.new.Types {
+		if !oldTypes[t.NewTypeName] {
+			c.add(AliasAdded, false, "types."+t.NewTypeName, "", t.Type)
+		}
+	}
+}
+
+//describeType is typ, followed by the type it resolves to if that differs
+func describeType(typ, resolved string) string {
+	if typ == resolved {
+		return typ
+	}
+	return typ + " (" + resolved + ")"
+}
+
+func resolveOrSelf(abi *ABI, typ string) string {
+	if resolved, err := resolveAbiType(abi, typ); err == nil {
+		return resolved
+	}
+	return typ
+}
+
+//compareType compares the encoding of oldType in the old abi with newType
+//in the new one
+func (c *abiComparer) compareType(path string, oldType, newType string) {
+	o, n := resolveOrSelf(c.old, oldType), resolveOrSelf(c.new, newType)
+
+	for _, suffix := range []string{"[]", "?"} {
+		if strings.HasSuffix(o, suffix) || strings.HasSuffix(n, suffix) {
+			if !strings.HasSuffix(o, suffix) || !strings.HasSuffix(n, suffix) {
+				c.add(TypeChanged, true, path, describeType(oldType, o), describeType(newType, n))
+				return
+			}
+			c.compareType(path+suffix, strings.TrimSuffix(o, suffix), strings.TrimSuffix(n, suffix))
+			return
+		}
+	}
+
+	oVariant, nVariant := getAbiVariant(c.old, o), getAbiVariant(c.new, n)
+	oStruct, nStruct := getAbiStruct(c.old, o) != nil, getAbiStruct(c.new, n) != nil
+	switch {
+	case oVariant != nil && nVariant != nil, oStruct && nStruct:
+	default:
+		if o != n || oVariant != nil || nVariant != nil || oStruct != nStruct {
+			c.add(TypeChanged, true, path, describeType(oldType, o), describeType(newType, n))
+		}
+		return
+	}
+
+	if o != n {
+		c.add(TypeRenamed, false, path, o, n)
+	}
+	key := o + "\x00" + n
+	if c.visited[key] {
+		return
+	}
+	c.visited[key] = true
+
+	if oVariant != nil {
+		c.compareVariant(oVariant, nVariant)
+		return
+	}
+	c.compareStruct(o, n)
+}
+
+func (c *abiComparer) compareVariant(o, n *ABIVariant) {
+	path := "variants." + n.Name + ".types"
+	for i := 0; i < len(o.Types) || i < len(n.Types); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(n.Types):
+			c.add(VariantTypeRemoved, true, itemPath, o.Types[i], "")
+		case i >= len(o.Types):
+			c.add(VariantTypeAppended, false, itemPath, "", n.Types[i])
+		default:
+			c.compareType(itemPath, o.Types[i], n.Types[i])
+		}
+	}
+}
+
+func (c *abiComparer) compareStruct(o, n string) {
+	path := "structs." + n + ".fields"
+	oldFields, err := abiStructFields(c.old, o)
+	if err != nil {
+		c.add(TypeChanged, true, "structs."+n, o, n)
+		return
+	}
+	newFields, err := abiStructFields(c.new, n)
+	if err != nil {
+		c.add(TypeChanged, true, "structs."+n, o, n)
+		return
+	}
+	oldPairs, newPairs := oldFields.GetStringPair(), newFields.GetStringPair()
+
+	newIndex := map[string]int{}
+	for i, pair := range newPairs {
+		newIndex[pair.Key] = i
+	}
+	oldIndex := map[string]int{}
+	var commonOld []string
+	lastCommon := -1
+	for i, pair := range oldPairs {
+		oldIndex[pair.Key] = i
+		j, ok := newIndex[pair.Key]
+		if !ok {
+			c.add(FieldRemoved, true, path+"."+pair.Key, pair.Value, "")
+			continue
+		}
+		commonOld = append(commonOld, pair.Key)
+		if j > lastCommon {
+			lastCommon = j
+		}
+	}
+
+	var commonNew []string
+	for i, pair := range newPairs {
+		if _, ok := oldIndex[pair.Key]; ok {
+			commonNew = append(commonNew, pair.Key)
+			continue
+		}
+		if i > lastCommon {
+			c.add(FieldAppended, true, path+"."+pair.Key, "", pair.Value)
+		} else {
+			c.add(FieldInserted, true, path+"."+pair.Key, "", pair.Value)
+		}
+	}
+
+	if strings.Join(commonOld, ",") != strings.Join(commonNew, ",") {
+		c.add(FieldReordered, true, path, strings.Join(commonOld, ","), strings.Join(commonNew, ","))
+		return
+	}
+	for _, name := range commonOld {
+		c.compareType(path+"."+name, oldPairs[oldIndex[name]].Value, newPairs[newIndex[name]].Value)
+	}
+}
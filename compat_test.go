@@ -0,0 +1,174 @@
+package msgpack
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompareAbi(t *testing.T) {
+	fmt.Println("TestCompareAbi...")
+
+	oldAbi, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "account_name", "type": "string"}, {"new_type_name": "amount", "type": "uint64"}, {"new_type_name": "memo", "type": "string"}],
+		"structs": [
+			{"name": "transfer", "base": "", "fields": {"from": "account_name", "to": "account_name", "value": "amount"}},
+			{"name": "item", "base": "", "fields": {"name": "string", "value": "uint64"}},
+			{"name": "additem", "base": "", "fields": {"owner": "string", "item": "item", "tags": "string[]"}},
+			{"name": "swap", "base": "", "fields": {"a": "string", "b": "string"}},
+			{"name": "remove", "base": "", "fields": {"a": "string", "b": "string", "c": "string"}},
+			{"name": "insert", "base": "", "fields": {"a": "string", "b": "string"}},
+			{"name": "textproposal", "base": "", "fields": {"title": "string"}},
+			{"name": "propose", "base": "", "fields": {"proposal": "proposal"}}
+		],
+		"variants": [{"name": "proposal", "types": ["textproposal", "uint32"]}],
+		"actions": [
+			{"action_name": "transfer", "type": "transfer"},
+			{"action_name": "additem", "type": "additem"},
+			{"action_name": "swap", "type": "swap"},
+			{"action_name": "remove", "type": "remove"},
+			{"action_name": "insert", "type": "insert"},
+			{"action_name": "propose", "type": "propose"},
+			{"action_name": "retired", "type": "swap"}
+		],
+		"tables": [{"table_name": "items", "index_type": "string", "key_names": ["name"], "key_types": ["string"], "type": "item"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAbi, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "account_name", "type": "string"}, {"new_type_name": "amount", "type": "uint32"}, {"new_type_name": "note", "type": "string"}],
+		"structs": [
+			{"name": "transfer", "base": "", "fields": {"from": "string", "to": "account_name", "value": "amount", "memo": "string?"}},
+			{"name": "asset", "base": "", "fields": {"name": "string", "value": "uint64"}},
+			{"name": "additem", "base": "", "fields": {"owner": "string", "item": "asset", "tags": "string"}},
+			{"name": "swap", "base": "", "fields": {"b": "string", "a": "string"}},
+			{"name": "remove", "base": "", "fields": {"a": "string", "c": "string", "d": "string"}},
+			{"name": "insert", "base": "", "fields": {"a": "string", "x": "string", "b": "string"}},
+			{"name": "textproposal", "base": "", "fields": {"title": "string"}},
+			{"name": "propose", "base": "", "fields": {"proposal": "proposal"}}
+		],
+		"variants": [{"name": "proposal", "types": ["textproposal", "uint32", "string"]}],
+		"actions": [
+			{"action_name": "transfer", "type": "transfer"},
+			{"action_name": "additem", "type": "additem"},
+			{"action_name": "swap", "type": "swap"},
+			{"action_name": "remove", "type": "remove"},
+			{"action_name": "insert", "type": "insert"},
+			{"action_name": "propose", "type": "propose"},
+			{"action_name": "burn", "type": "swap"}
+		],
+		"tables": [{"table_name": "assets", "index_type": "string", "key_names": ["name"], "key_types": ["string"], "type": "asset"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range CompareAbi(oldAbi, newAbi) {
+		got = append(got, c.String())
+	}
+	expect := []string{
+		"breaking: structs.transfer.fields.memo: field appended: string?",
+		"breaking: structs.transfer.fields.value: type changed: amount (uint64) -> amount (uint32)",
+		"compatible: structs.additem.fields.item: type renamed: item -> asset",
+		"breaking: structs.additem.fields.tags: type changed: string[] -> string",
+		"breaking: structs.swap.fields: fields reordered: a,b -> b,a",
+		"breaking: structs.remove.fields.b: field removed: string",
+		"breaking: structs.remove.fields.d: field appended: string",
+		"breaking: structs.insert.fields.x: field inserted: string",
+		"compatible: variants.proposal.types[2]: variant type appended: string",
+		"breaking: actions.retired: action removed: swap",
+		"compatible: actions.burn: action added: swap",
+		"breaking: tables.items: table removed: item",
+		"compatible: tables.assets: table added: asset",
+		"breaking: types.amount: alias changed: uint64 -> uint32",
+		"compatible: types.memo: alias removed: string",
+		"compatible: types.note: alias added: string",
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Fatalf("CompareAbi:\n%s", strings.Join(got, "\n"))
+	}
+
+	if changes := CompareAbi(oldAbi, oldAbi); len(changes) != 0 {
+		t.Fatalf("CompareAbi: same abi: %v", changes)
+	}
+}
+
+func TestCompareAbiAlias(t *testing.T) {
+	fmt.Println("TestCompareAbiAlias...")
+
+	oldAbi, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "account_name", "type": "string"}],
+		"structs": [{"name": "node", "base": "", "fields": {"owner": "account_name", "next": "node?"}}],
+		"actions": [{"action_name": "link", "type": "node"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAbi, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "name", "type": "string"}, {"new_type_name": "account_name", "type": "name"}],
+		"structs": [{"name": "node", "base": "", "fields": {"owner": "account_name", "next": "node?"}}],
+		"actions": [{"action_name": "link", "type": "node"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := CompareAbi(oldAbi, newAbi)
+	if len(changes) != 2 || changes[0].Kind != AliasChanged || changes[0].Breaking || changes[1].Kind != AliasAdded {
+		t.Fatalf("CompareAbi: %v", changes)
+	}
+}
+
+func TestCompareAbiAppendedField(t *testing.T) {
+	fmt.Println("TestCompareAbiAppendedField...")
+
+	oldAbi, err := ParseAbi([]byte(`{
+		"structs": [{"name": "s", "base": "", "fields": {"a": "string"}}],
+		"actions": [{"action_name": "s", "type": "s"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAbi, err := ParseAbi([]byte(`{
+		"structs": [{"name": "s", "base": "", "fields": {"a": "string", "b": "string?"}}],
+		"actions": [{"action_name": "s", "type": "s"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := CompareAbi(oldAbi, newAbi)
+	if len(changes) != 1 || changes[0].Kind != FieldAppended || !changes[0].Breaking {
+		t.Fatalf("CompareAbi: %v", changes)
+	}
+
+	oldData, err := MarshalAbiEx(map[string]interface{}{"a": "x"}, oldAbi, "", "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newData, err := MarshalAbiEx(map[string]interface{}{"a": "x", "b": nil}, newAbi, "", "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = UnmarshalAbiEx(oldData, newAbi, "", "s"); err == nil {
+		t.Error("old payload decoded with the new abi")
+	}
+	if _, err = UnmarshalAbiEx(newData, oldAbi, "", "s"); err == nil {
+		t.Error("new payload decoded with the old abi")
+	}
+	compiled, err := CompileAbi(newAbi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = compiled.Decode("s", oldData); err == nil {
+		t.Error("old payload decoded with the compiled new abi")
+	}
+
+	migrated, err := MigratePayload(oldData, oldAbi, newAbi, "s", nil)
+	if err != nil || !bytes.Equal(migrated, newData) {
+		t.Errorf("MigratePayload: %x %v", migrated, err)
+	}
+}
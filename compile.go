@@ -0,0 +1,661 @@
+package msgpack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type planKind int
+
+const (
+	planString planKind = iota
+	planUint8
+	planUint16
+	planUint32
+	planUint64
+	planBytes
+	planStruct
+	planArray
+	planOptional
+	planVariant
+)
+
+var planKinds = map[string]planKind{
+	"string": planString,
+	"uint8":  planUint8,
+	"uint16": planUint16,
+	"uint32": planUint32,
+	"uint64": planUint64,
+	"bytes":  planBytes,
+}
+
+//planType is a resolved abi type
+type planType struct {
+	name     string
+	kind     planKind
+	elem     *planType   // planArray, planOptional
+	fields   *structPlan // planStruct
+	variants []*planType // planVariant
+	vnames   []string    // planVariant, member types as declared
+}
+
+//structPlan is a resolved abi struct, base fields included
+type structPlan struct {
+	name   string
+	names  []string
+	types  []*planType
+	index  map[string]int
+	goMaps sync.Map // reflect.Type -> []int, Go field index of each abi field
+}
+
+//CompiledAbi is an abi with actions, structs, aliases, bases and variants
+//resolved once into an indexed plan, for fast repeated encoding and decoding.
+//It is safe for concurrent use.
+type CompiledAbi struct {
+	abi     *ABI
+	actions map[string]*structPlan
+	types   map[string]*planType
+}
+
+//CompileAbi validates abi and compiles it. abi must not be modified afterwards.
+func CompileAbi(abi *ABI) (*CompiledAbi, error) {
+	if abi == nil {
+		return nil, fmt.Errorf("CompileAbi: abi is nil")
+	}
+	if err := abi.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &CompiledAbi{
+		abi:     abi,
+		actions: map[string]*structPlan{},
+		types:   map[string]*planType{},
+	}
+	for _, action := range abi.Actions {
+		t, err := c.compileType(action.Type)
+		if err != nil {
+			return nil, fmt.Errorf("CompileAbi: action %s: %v", action.ActionName, err)
+		}
+		c.actions[action.ActionName] = t.fields
+	}
+	return c, nil
+}
+
+//Abi returns the abi c was compiled from
+func (c *CompiledAbi) Abi() *ABI {
+	return c.abi
+}
+
+func (c *CompiledAbi) compileType(typ string) (*planType, error) {
+	resolved, err := resolveAbiType(c.abi, typ)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := c.types[resolved]; ok {
+		return t, nil
+	}
+
+	t := &planType{name: resolved}
+	// registered before compiling members, so recursive types terminate
+	c.types[resolved] = t
+
+	switch {
+	case strings.HasSuffix(resolved, "[]"):
+		t.kind = planArray
+		t.elem, err = c.compileType(strings.TrimSuffix(resolved, "[]"))
+	case strings.HasSuffix(resolved, "?"):
+		t.kind = planOptional
+		t.elem, err = c.compileType(strings.TrimSuffix(resolved, "?"))
+	default:
+		if kind, ok := planKinds[resolved]; ok {
+			t.kind = kind
+		} else if variant := getAbiVariant(c.abi, resolved); variant != nil {
+			t.kind = planVariant
+			t.variants = make([]*planType, len(variant.Types))
+			t.vnames = variant.Types
+			for i, vt := range variant.Types {
+				if t.variants[i], err = c.compileType(vt); err != nil {
+					break
+				}
+			}
+		} else {
+			t.kind = planStruct
+			t.fields, err = c.compileStruct(resolved)
+		}
+	}
+	if err != nil {
+		delete(c.types, resolved)
+		return nil, err
+	}
+	return t, nil
+}
+
+func (c *CompiledAbi) compileStruct(name string) (*structPlan, error) {
+	fields, err := abiStructFields(c.abi, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &structPlan{name: name, index: map[string]int{}}
+	for _, pair := range fields.GetStringPair() {
+		t, err := c.compileType(pair.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", name, pair.Key, err)
+		}
+		s.index[pair.Key] = len(s.names)
+		s.names = append(s.names, pair.Key)
+		s.types = append(s.types, t)
+	}
+	return s, nil
+}
+
+func (c *CompiledAbi) action(method string) (*structPlan, error) {
+	s, ok := c.actions[method]
+	if !ok {
+		return nil, fmt.Errorf("CompiledAbi: undefined action %s", method)
+	}
+	return s, nil
+}
+
+//Encode is to serialize the parameters of method. v is a
+//map[string]interface{}, a *FeildMap, or a struct or pointer to struct whose
+//fields are named by msgpack or json tags. Fields are written in abi order.
+func (c *CompiledAbi) Encode(method string, v interface{}) ([]byte, error) {
+	s, err := c.action(method)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	writer := &bytes.Buffer{}
+	if err = encodePlanAny(writer, &planType{name: s.name, kind: planStruct, fields: s}, v); err != nil {
+		return []byte{}, fmt.Errorf("CompiledAbi.Encode %s: %v", method, err)
+	}
+	return writer.Bytes(), nil
+}
+
+//Decode is to unserialize the parameters of method into an ordered FeildMap,
+//with the same value types as DecodeAbiEx
+func (c *CompiledAbi) Decode(method string, data []byte) (*FeildMap, error) {
+	s, err := c.action(method)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := decodePlanStruct(bytes.NewReader(data), s)
+	if err != nil {
+		return nil, fmt.Errorf("CompiledAbi.Decode %s: %v", method, err)
+	}
+	return val, nil
+}
+
+//DecodeInto is to unserialize the parameters of method into the struct
+//pointed to by v, with the same type checks as DecodeAbi
+func (c *CompiledAbi) DecodeInto(method string, data []byte, v interface{}) error {
+	s, err := c.action(method)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("CompiledAbi.DecodeInto: dst Not Settable %T", v)
+	}
+	if err = decodePlanInto(bytes.NewReader(data), &planType{name: s.name, kind: planStruct, fields: s}, rv.Elem()); err != nil {
+		return fmt.Errorf("CompiledAbi.DecodeInto %s: %v", method, err)
+	}
+	return nil
+}
+
+//goFields maps the abi fields of s to the fields of Go struct type t
+func (s *structPlan) goFields(t reflect.Type) ([]int, error) {
+	if m, ok := s.goMaps.Load(t); ok {
+		return m.([]int), nil
+	}
+
+	m := make([]int, len(s.names))
+	for i := range m {
+		m[i] = -1
+	}
+	for i := 0; i < t.NumField(); i++ {
+		name := abiFieldName(t.Field(i))
+		j, ok := s.index[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not in abi struct %s", name, s.name)
+		}
+		m[j] = i
+	}
+	for j, i := range m {
+		if i < 0 {
+			return nil, fmt.Errorf("abi field %s.%s not found in %v", s.name, s.names[j], t)
+		}
+	}
+
+	s.goMaps.Store(t, m)
+	return m, nil
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func encodePlanStruct(w io.Writer, s *structPlan, v reflect.Value) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		return fmt.Errorf("%s is nil", s.name)
+	}
+
+	if v.Type() == reflect.TypeOf(FeildMap{}) && v.CanInterface() {
+		v = reflect.ValueOf(v.Interface().(FeildMap).values)
+	}
+
+	PackArraySize(w, uint16(len(s.names)))
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("Unsupported Type: %v", v.Type())
+		}
+		if v.Len() != len(s.names) {
+			return fmt.Errorf("%s: fields number mismatch! abi: %d, value: %d", s.name, len(s.names), v.Len())
+		}
+		for i, name := range s.names {
+			fv := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !fv.IsValid() {
+				return fmt.Errorf("%s: field %s not found", s.name, name)
+			}
+			if err := encodePlanValue(w, s.types[i], fv); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	case reflect.Struct:
+		m, err := s.goFields(v.Type())
+		if err != nil {
+			return err
+		}
+		for i, name := range s.names {
+			if err := encodePlanValue(w, s.types[i], v.Field(m[i])); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("Unsupported Type: %v for %s", v.Type(), s.name)
+	}
+	return nil
+}
+
+//encodePlanFast encodes the common map input types without reflection. It
+//reports false when v has to go through encodePlanValue.
+func encodePlanFast(w io.Writer, t *planType, v interface{}) (bool, error) {
+	switch t.kind {
+	case planString:
+		if s, ok := v.(string); ok {
+			PackStr16(w, s)
+			return true, nil
+		}
+	case planUint64:
+		if u, ok := v.(uint64); ok {
+			PackUint64(w, u)
+			return true, nil
+		}
+	case planBytes:
+		if b, ok := v.([]byte); ok {
+			PackBin16(w, b)
+			return true, nil
+		}
+	case planOptional:
+		if v == nil {
+			_, err := PackNil(w)
+			return true, err
+		}
+		return encodePlanFast(w, t.elem, v)
+	case planArray:
+		if a, ok := v.([]interface{}); ok {
+			if err := packArrayLen(w, len(a)); err != nil {
+				return true, err
+			}
+			for _, e := range a {
+				if err := encodePlanAny(w, t.elem, e); err != nil {
+					return true, err
+				}
+			}
+			return true, nil
+		}
+	case planStruct:
+		if fm, ok := v.(*FeildMap); ok && fm != nil {
+			v = fm.values
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			s := t.fields
+			if len(m) != len(s.names) {
+				return true, fmt.Errorf("%s: fields number mismatch! abi: %d, value: %d", s.name, len(s.names), len(m))
+			}
+			PackArraySize(w, uint16(len(s.names)))
+			for i, name := range s.names {
+				fv, ok := m[name]
+				if !ok {
+					return true, fmt.Errorf("%s: field %s not found", s.name, name)
+				}
+				if err := encodePlanAny(w, s.types[i], fv); err != nil {
+					return true, fmt.Errorf("%s: %v", name, err)
+				}
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func encodePlanAny(w io.Writer, t *planType, v interface{}) error {
+	if ok, err := encodePlanFast(w, t, v); ok {
+		return err
+	}
+	return encodePlanValue(w, t, reflect.ValueOf(v))
+}
+
+func encodePlanValue(w io.Writer, t *planType, v reflect.Value) error {
+	if v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() && v.CanInterface() {
+		return encodePlanAny(w, t, v.Elem().Interface())
+	}
+
+	if t.kind == planOptional {
+		v = indirect(v)
+		if !v.IsValid() {
+			_, err := PackNil(w)
+			return err
+		}
+		return encodePlanValue(w, t.elem, v)
+	}
+
+	if t.kind != planStruct {
+		v = indirect(v)
+		if !v.IsValid() {
+			return fmt.Errorf("value of %s is nil", t.name)
+		}
+	}
+
+	switch t.kind {
+	case planString:
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("abiType %s mismatch to %v", t.name, v.Type())
+		}
+		PackStr16(w, v.String())
+	case planUint8, planUint16, planUint32, planUint64:
+		switch v.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		default:
+			return fmt.Errorf("abiType %s mismatch to %v", t.name, v.Type())
+		}
+		u := v.Uint()
+		switch t.kind {
+		case planUint8:
+			if u > 0xff {
+				return fmt.Errorf("%d overflows %s", u, t.name)
+			}
+			PackUint8(w, uint8(u))
+		case planUint16:
+			if u > 0xffff {
+				return fmt.Errorf("%d overflows %s", u, t.name)
+			}
+			PackUint16(w, uint16(u))
+		case planUint32:
+			if u > 0xffffffff {
+				return fmt.Errorf("%d overflows %s", u, t.name)
+			}
+			PackUint32(w, uint32(u))
+		default:
+			PackUint64(w, u)
+		}
+	case planBytes:
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("abiType %s mismatch to %v", t.name, v.Type())
+		}
+		PackBin16(w, v.Bytes())
+	case planArray:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Errorf("abiType %s mismatch to %v", t.name, v.Type())
+		}
+		if err := packArrayLen(w, v.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodePlanValue(w, t.elem, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case planVariant:
+		if v.Type() != variantType || !v.CanInterface() {
+			return fmt.Errorf("variant %s mismatch to %v", t.name, v.Type())
+		}
+		vv := v.Interface().(Variant)
+		index := -1
+		for i, name := range t.vnames {
+			if name == vv.Type {
+				index = i
+				break
+			}
+		}
+		if index < 0 || index >= REGULAR_UINT8_MAX {
+			return fmt.Errorf("type %s is not in variant %s %v", vv.Type, t.name, t.vnames)
+		}
+		PackArraySize(w, 2)
+		PackUint8(w, uint8(index))
+		return encodePlanValue(w, t.variants[index], reflect.ValueOf(vv.Value))
+	case planStruct:
+		return encodePlanStruct(w, t.fields, v)
+	}
+	return nil
+}
+
+func decodePlanStruct(r io.Reader, s *structPlan) (*FeildMap, error) {
+	size, err := UnpackArraySize(r)
+	if err != nil {
+		return nil, err
+	}
+	if int(size) != len(s.names) {
+		return nil, fmt.Errorf("%s: fields number mismatch! abi: %d, data: %d", s.name, len(s.names), size)
+	}
+
+	value := New()
+	for i, name := range s.names {
+		val, err := decodePlanValue(r, s.types[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		value.Set(name, val)
+	}
+	return value, nil
+}
+
+func decodePlanValue(r io.Reader, t *planType) (interface{}, error) {
+	switch t.kind {
+	case planString:
+		return UnpackStr16(r)
+	case planUint8:
+		return UnpackUint8(r)
+	case planUint16:
+		return UnpackUint16(r)
+	case planUint32:
+		return UnpackUint32(r)
+	case planUint64:
+		return UnpackUint64(r)
+	case planBytes:
+		return UnpackBin16(r)
+	case planStruct:
+		return decodePlanStruct(r, t.fields)
+	case planOptional:
+		vr, err := unpackOptional(r)
+		if vr == nil || err != nil {
+			return nil, err
+		}
+		return decodePlanValue(vr, t.elem)
+	case planArray:
+		size, err := UnpackArraySize(r)
+		if err != nil {
+			return nil, err
+		}
+		vals := make([]interface{}, size)
+		for i := range vals {
+			if vals[i], err = decodePlanValue(r, t.elem); err != nil {
+				return nil, err
+			}
+		}
+		return vals, nil
+	case planVariant:
+		size, err := UnpackArraySize(r)
+		if err != nil {
+			return nil, err
+		}
+		if size != 2 {
+			return nil, fmt.Errorf("variant %s: array size %d, want 2", t.name, size)
+		}
+		index, err := UnpackUint8(r)
+		if err != nil {
+			return nil, err
+		}
+		if int(index) >= len(t.variants) {
+			return nil, fmt.Errorf("variant %s: type index %d out of range", t.name, index)
+		}
+		val, err := decodePlanValue(r, t.variants[index])
+		if err != nil {
+			return nil, err
+		}
+		return Variant{Type: t.vnames[index], Value: val}, nil
+	}
+	return nil, fmt.Errorf("Unsupported Type: %s", t.name)
+}
+
+func decodePlanInto(r io.Reader, t *planType, v reflect.Value) error {
+	mismatch := func() error {
+		return fmt.Errorf("abiType %s mismatch to %v", t.name, v.Type())
+	}
+
+	switch t.kind {
+	case planOptional:
+		if v.Kind() != reflect.Ptr {
+			return mismatch()
+		}
+		vr, err := unpackOptional(r)
+		if err != nil {
+			return err
+		}
+		if vr == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		val := reflect.New(v.Type().Elem())
+		if err = decodePlanInto(vr, t.elem, val.Elem()); err != nil {
+			return err
+		}
+		v.Set(val)
+		return nil
+	case planStruct, planVariant:
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+	}
+
+	switch t.kind {
+	case planString:
+		if v.Kind() != reflect.String {
+			return mismatch()
+		}
+		val, err := UnpackStr16(r)
+		v.SetString(val)
+		return err
+	case planUint8, planUint16, planUint32, planUint64:
+		var val uint64
+		var err error
+		var kind reflect.Kind
+		switch t.kind {
+		case planUint8:
+			var u uint8
+			u, err = UnpackUint8(r)
+			val, kind = uint64(u), reflect.Uint8
+		case planUint16:
+			var u uint16
+			u, err = UnpackUint16(r)
+			val, kind = uint64(u), reflect.Uint16
+		case planUint32:
+			var u uint32
+			u, err = UnpackUint32(r)
+			val, kind = uint64(u), reflect.Uint32
+		default:
+			val, err = UnpackUint64(r)
+			kind = reflect.Uint64
+		}
+		if v.Kind() != kind {
+			return mismatch()
+		}
+		v.SetUint(val)
+		return err
+	case planBytes:
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+			return mismatch()
+		}
+		val, err := UnpackBin16(r)
+		v.SetBytes(val)
+		return err
+	case planArray:
+		if v.Kind() != reflect.Slice {
+			return mismatch()
+		}
+		size, err := UnpackArraySize(r)
+		if err != nil {
+			return err
+		}
+		vals := reflect.MakeSlice(v.Type(), int(size), int(size))
+		for i := 0; i < int(size); i++ {
+			if err = decodePlanInto(r, t.elem, vals.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(vals)
+		return nil
+	case planVariant:
+		if v.Type() != variantType {
+			return mismatch()
+		}
+		val, err := decodePlanValue(r, t)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(val))
+		return nil
+	case planStruct:
+		if v.Kind() != reflect.Struct {
+			return mismatch()
+		}
+		size, err := UnpackArraySize(r)
+		if err != nil {
+			return err
+		}
+		s := t.fields
+		if int(size) != len(s.names) {
+			return fmt.Errorf("%s: fields number mismatch! abi: %d, data: %d", s.name, len(s.names), size)
+		}
+		m, err := s.goFields(v.Type())
+		if err != nil {
+			return err
+		}
+		for i, name := range s.names {
+			if err = decodePlanInto(r, s.types[i], v.Field(m[i])); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		return nil
+	}
+	return mismatch()
+}
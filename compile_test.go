@@ -0,0 +1,266 @@
+package msgpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+const compileTestAbi = `{
+	"types": [{"new_type_name": "account_name", "type": "string"}],
+	"structs": [
+		{"name": "item", "base": "", "fields": {"name": "string", "value": "uint64"}},
+		{"name": "transferbase", "base": "", "fields": {"from": "account_name"}},
+		{"name": "batchtransfer", "base": "transferbase", "fields": {"to": "account_name[]", "values": "uint64[]", "memo": "string?", "items": "item[]", "extra": "item?"}},
+		{"name": "textproposal", "base": "", "fields": {"title": "string"}},
+		{"name": "paramproposal", "base": "", "fields": {"key": "string", "value": "uint64"}},
+		{"name": "propose", "base": "", "fields": {"proposer": "string", "proposal": "proposal"}}
+	],
+	"variants": [{"name": "proposal", "types": ["textproposal", "paramproposal"]}],
+	"actions": [
+		{"action_name": "batchtransfer", "type": "batchtransfer"},
+		{"action_name": "propose", "type": "propose"}
+	]
+}`
+
+func TestCompileAbi(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Value uint64 `json:"value"`
+	}
+
+	type BatchTransfer struct {
+		From   string   `json:"from"`
+		To     []string `json:"to"`
+		Values []uint64 `json:"values"`
+		Memo   *string  `json:"memo"`
+		Items  []Item   `json:"items"`
+		Extra  *Item    `json:"extra"`
+	}
+
+	fmt.Println("TestCompileAbi...")
+
+	abi, err := ParseAbi([]byte(compileTestAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := CompileAbi(abi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memo := "memo"
+	bt := BatchTransfer{
+		From:   "bottos",
+		To:     []string{"a", "b"},
+		Values: []uint64{1, 2},
+		Memo:   &memo,
+		Items:  []Item{{Name: "x", Value: 3}},
+	}
+	expect, err := MarshalAbi(bt, abi, "bottos", "batchtransfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := c.Encode("batchtransfer", &bt)
+	if err != nil || BytesToHex(b) != BytesToHex(expect) {
+		t.Fatalf("Encode struct: %x %v", b, err)
+	}
+
+	b, err = c.Encode("batchtransfer", map[string]interface{}{
+		"from":   "bottos",
+		"to":     []interface{}{"a", "b"},
+		"values": []uint64{1, 2},
+		"memo":   "memo",
+		"items":  []interface{}{map[string]interface{}{"name": "x", "value": uint64(3)}},
+		"extra":  nil,
+	})
+	if err != nil || BytesToHex(b) != BytesToHex(expect) {
+		t.Fatalf("Encode map: %x %v", b, err)
+	}
+
+	fm, err := c.Decode("batchtransfer", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, _ := json.Marshal(fm)
+	if string(js) != `{"from":"bottos","to":["a","b"],"values":[1,2],"memo":"memo","items":[{"name":"x","value":3}],"extra":null}` {
+		t.Fatalf("Decode: %s", js)
+	}
+
+	b, err = c.Encode("batchtransfer", fm)
+	if err != nil || BytesToHex(b) != BytesToHex(expect) {
+		t.Fatalf("Encode FeildMap: %x %v", b, err)
+	}
+
+	bt1 := BatchTransfer{}
+	err = c.DecodeInto("batchtransfer", b, &bt1)
+	if err != nil || *bt1.Memo != "memo" || bt1.Extra != nil || len(bt1.To) != 2 || bt1.Items[0].Value != 3 {
+		t.Fatalf("DecodeInto: %v %v", bt1, err)
+	}
+
+	_, err = c.Encode("batchtransfer", map[string]interface{}{"from": "bottos"})
+	if err == nil {
+		t.Fatal("Encode: expected fields number mismatch error")
+	}
+	_, err = c.Encode("nosuchaction", bt)
+	if err == nil {
+		t.Fatal("Encode: expected undefined action error")
+	}
+	err = c.DecodeInto("batchtransfer", b, &Item{})
+	if err == nil {
+		t.Fatal("DecodeInto: expected field mismatch error")
+	}
+}
+
+func TestCompileAbiFieldOrder(t *testing.T) {
+	// Go field order differs from the abi order item{name, value}
+	type Item struct {
+		Value uint8  `json:"value"`
+		Name  string `json:"name"`
+	}
+
+	fmt.Println("TestCompileAbiFieldOrder...")
+
+	abi, err := ParseAbi([]byte(`{
+		"structs": [{"name": "item", "base": "", "fields": {"name": "string", "value": "uint8"}}],
+		"actions": [{"action_name": "item", "type": "item"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := CompileAbi(abi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect, err := MarshalAbiEx(map[string]interface{}{"name": "x", "value": uint8(7)}, abi, "", "item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalAbi(Item{Value: 7, Name: "x"}, abi, "", "item")
+	if err != nil || BytesToHex(b) != BytesToHex(expect) {
+		t.Fatalf("MarshalAbi: %x %v, want %x", b, err, expect)
+	}
+	b, err = c.Encode("item", Item{Value: 7, Name: "x"})
+	if err != nil || BytesToHex(b) != BytesToHex(expect) {
+		t.Fatalf("Encode: %x %v, want %x", b, err, expect)
+	}
+
+	type Wide struct {
+		Name  string `json:"name"`
+		Value uint64 `json:"value"`
+	}
+	if _, err = MarshalAbi(Wide{Name: "x", Value: 256}, abi, "", "item"); err == nil {
+		t.Fatal("MarshalAbi: expected overflow error")
+	}
+	if _, err = c.Encode("item", Wide{Name: "x", Value: 256}); err == nil {
+		t.Fatal("Encode: expected overflow error")
+	}
+	if _, err = MarshalAbi(struct {
+		Name string `json:"name"`
+	}{"x"}, abi, "", "item"); err == nil {
+		t.Fatal("MarshalAbi: expected missing field error")
+	}
+}
+
+func TestCompileAbiVariant(t *testing.T) {
+	type Propose struct {
+		Proposer string   `json:"proposer"`
+		Proposal *Variant `json:"proposal"`
+	}
+
+	fmt.Println("TestCompileAbiVariant...")
+
+	abi, err := ParseAbi([]byte(compileTestAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := CompileAbi(abi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "dc0002da0006626f74746f73dc0002cc01dc0002da0003666565cf000000000000000a"
+	b, err := c.Encode("propose", map[string]interface{}{
+		"proposer": "bottos",
+		"proposal": Variant{Type: "paramproposal", Value: map[string]interface{}{"key": "fee", "value": uint64(10)}},
+	})
+	if err != nil || BytesToHex(b) != expect {
+		t.Fatalf("Encode: %x %v", b, err)
+	}
+
+	p := Propose{}
+	err = c.DecodeInto("propose", b, &p)
+	if err != nil || p.Proposal.Type != "paramproposal" {
+		t.Fatalf("DecodeInto: %v %v", p, err)
+	}
+	if v, _ := p.Proposal.Value.(*FeildMap).Get("value"); v != uint64(10) {
+		t.Fatalf("DecodeInto: variant value %v", v)
+	}
+
+	_, err = c.Encode("propose", Propose{Proposer: "bottos", Proposal: &Variant{Type: "nosuchproposal"}})
+	if err == nil {
+		t.Fatal("Encode: expected unknown variant type error")
+	}
+	_, err = c.Encode("propose", map[string]interface{}{
+		"proposer": "bottos",
+		"proposal": Variant{Type: "paramproposal", Value: map[string]interface{}{"key": "fee", "value": uint64(10), "extra": "x"}},
+	})
+	if err == nil {
+		t.Fatal("Encode: expected fields number mismatch error")
+	}
+}
+
+func TestCompileAbiInvalid(t *testing.T) {
+	fmt.Println("TestCompileAbiInvalid...")
+
+	abi, err := ParseAbi([]byte(`{
+		"structs": [{"name": "transfer", "base": "", "fields": {"from": "nosuchtype"}}],
+		"actions": [{"action_name": "transfer", "type": "transfer"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CompileAbi(abi); err == nil {
+		t.Fatal("CompileAbi: expected undefined type error")
+	}
+	if _, err = CompileAbi(nil); err == nil {
+		t.Fatal("CompileAbi: expected nil abi error")
+	}
+}
+
+func BenchmarkCompiledAbiEncode(b *testing.B) {
+	abi, _ := ParseAbi([]byte(compileTestAbi))
+	c, _ := CompileAbi(abi)
+	v := map[string]interface{}{
+		"from":   "bottos",
+		"to":     []interface{}{"a", "b"},
+		"values": []uint64{1, 2},
+		"memo":   "memo",
+		"items":  []interface{}{map[string]interface{}{"name": "x", "value": uint64(3)}},
+		"extra":  nil,
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Encode("batchtransfer", v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalAbiEx(b *testing.B) {
+	abi, _ := ParseAbi([]byte(compileTestAbi))
+	v := map[string]interface{}{
+		"from":   "bottos",
+		"to":     []interface{}{"a", "b"},
+		"values": []uint64{1, 2},
+		"memo":   "memo",
+		"items":  []interface{}{map[string]interface{}{"name": "x", "value": uint64(3)}},
+		"extra":  nil,
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := MarshalAbiEx(v, abi, "bottos", "batchtransfer"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
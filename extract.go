@@ -0,0 +1,121 @@
+package msgpack
+
+import (
+	"bytes"
+	"fmt"
+)
+
+//Extract returns the raw encoding of the value found by walking path through
+//nested arrays and maps, without decoding anything else. Each element of path
+//is an array index, or for maps the index of the entry whose value is taken.
+//An empty path returns the first value in data.
+func Extract(data []byte, path ...int) (RawMessage, error) {
+	r := bytes.NewReader(data)
+
+	for depth, index := range path {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		var size uint64
+		switch {
+		case c > POSFIXNUMMAX && c <= FIXARRAYMAX: // fixmap or fixarray
+			size = uint64(c & FIRSTBYTEMASK)
+		case c == ARRAY16 || c == MAP16:
+			size, err = readLength(r, 2)
+		case c == ARRAY32 || c == MAP32:
+			size, err = readLength(r, 4)
+		default:
+			return nil, fmt.Errorf("Extract: path[%d] is %v, not array or map", depth, typeOf(c))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if index < 0 || uint64(index) >= size {
+			return nil, fmt.Errorf("Extract: path[%d] index %d out of range [0, %d)", depth, index, size)
+		}
+
+		skip := index
+		if typeOf(c) == MapType {
+			// skip the preceding entries and the key of the selected one
+			skip = 2*index + 1
+		}
+		for i := 0; i < skip; i++ {
+			if err = skipValue(r, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	start := len(data) - r.Len()
+	if err := skipValue(r, nil); err != nil {
+		return nil, err
+	}
+	end := len(data) - r.Len()
+
+	return RawMessage(data[start:end]), nil
+}
+
+//ExtractUint64 extracts an unsigned integer of any width at path
+func ExtractUint64(data []byte, path ...int) (uint64, error) {
+	raw, err := Extract(data, path...)
+	if err != nil {
+		return 0, err
+	}
+
+	c := raw[0]
+	switch {
+	case c <= POSFIXNUMMAX:
+		return uint64(c), nil
+	case c == UINT8:
+		return readLength(bytes.NewReader(raw[1:]), 1)
+	case c == UINT16:
+		return readLength(bytes.NewReader(raw[1:]), 2)
+	case c == UINT32:
+		return readLength(bytes.NewReader(raw[1:]), 4)
+	case c == UINT64:
+		return readLength(bytes.NewReader(raw[1:]), 8)
+	}
+	return 0, fmt.Errorf("ExtractUint64: value is %v, not uint", typeOf(c))
+}
+
+//ExtractString extracts a string at path
+func ExtractString(data []byte, path ...int) (string, error) {
+	raw, err := Extract(data, path...)
+	if err != nil {
+		return "", err
+	}
+
+	if typeOf(raw[0]) != StrType {
+		return "", fmt.Errorf("ExtractString: value is %v, not str", typeOf(raw[0]))
+	}
+	return string(payloadOf(raw)), nil
+}
+
+//ExtractBytes extracts a byte array at path
+func ExtractBytes(data []byte, path ...int) ([]byte, error) {
+	raw, err := Extract(data, path...)
+	if err != nil {
+		return nil, err
+	}
+
+	if typeOf(raw[0]) != BinType {
+		return nil, fmt.Errorf("ExtractBytes: value is %v, not bin", typeOf(raw[0]))
+	}
+	return payloadOf(raw), nil
+}
+
+//payloadOf strips the header of a complete str or bin value
+func payloadOf(raw []byte) []byte {
+	switch raw[0] {
+	case STR8, BIN8:
+		return raw[2:]
+	case STR16, BIN16:
+		return raw[3:]
+	case STR32, BIN32:
+		return raw[5:]
+	}
+	return raw[1:]
+}
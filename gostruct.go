@@ -0,0 +1,166 @@
+package msgpack
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//AbiFromStructs builds an abi from Go param structs, keyed by action name.
+//Each value is a struct or a pointer to one. Field names come from msgpack or
+//json tags as in MarshalAbi, and fields keep the order of the Go struct.
+//Named struct types become abi structs named by AbiStructName, including
+//types defined from another struct type; pointers become optionals `T?`,
+//slices arrays `T[]` and []byte bytes.
+func AbiFromStructs(actions map[string]interface{}) (*ABI, error) {
+	g := &structAbiGen{
+		abi:   newAbi(),
+		names: map[string]reflect.Type{},
+	}
+
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := reflect.TypeOf(actions[name])
+		if t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("AbiFromStructs: action %s: %v is not a struct", name, t)
+		}
+
+		structName := name
+		if t.Name() != "" {
+			structName = AbiStructName(t.Name())
+		}
+		if err := g.addStruct(structName, t); err != nil {
+			return nil, fmt.Errorf("AbiFromStructs: action %s: %v", name, err)
+		}
+		g.abi.Actions = append(g.abi.Actions, ABIAction{ActionName: name, Type: structName})
+	}
+
+	if err := g.abi.Validate(); err != nil {
+		return nil, err
+	}
+	return g.abi, nil
+}
+
+type structAbiGen struct {
+	abi   *ABI
+	names map[string]reflect.Type
+}
+
+func (g *structAbiGen) addStruct(name string, t reflect.Type) error {
+	if prev, ok := g.names[name]; ok {
+		if prev != t {
+			return fmt.Errorf("struct name %s used by both %v and %v", name, prev, t)
+		}
+		return nil
+	}
+	g.names[name] = t
+
+	fields := New()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			return fmt.Errorf("%v.%s is unexported", t, sf.Name)
+		}
+		if sf.Anonymous {
+			return fmt.Errorf("%v.%s: embedded fields are not supported", t, sf.Name)
+		}
+
+		typ, err := g.abiType(sf.Type)
+		if err != nil {
+			return fmt.Errorf("%v.%s: %v", t, sf.Name, err)
+		}
+		fieldName := abiFieldName(sf)
+		if _, ok := fields.Get(fieldName); ok {
+			return fmt.Errorf("%v: duplicate field %s", t, fieldName)
+		}
+		fields.Set(fieldName, typ)
+	}
+
+	g.abi.Structs = append(g.abi.Structs, ABIStruct{Name: name, Fields: fields})
+	return nil
+}
+
+func (g *structAbiGen) abiType(t reflect.Type) (string, error) {
+	if typ, ok := AbiBasicType(t.Kind().String()); ok {
+		return typ, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if t.Elem().Kind() == reflect.Ptr {
+			return "", fmt.Errorf("unsupported type %v", t)
+		}
+		elem, err := g.abiType(t.Elem())
+		return elem + "?", err
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil
+		}
+		elem, err := g.abiType(t.Elem())
+		return elem + "[]", err
+	case reflect.Struct:
+		if t == variantType {
+			return "", fmt.Errorf("variant members can not be derived from %v", t)
+		}
+		if t.Name() == "" {
+			return "", fmt.Errorf("anonymous struct %v", t)
+		}
+		name := AbiStructName(t.Name())
+		return name, g.addStruct(name, t)
+	}
+	return "", fmt.Errorf("unsupported type %v", t)
+}
+
+//AbiFieldName returns the abi field name of a Go struct field named name
+//with tag: its msgpack tag, then its json tag, then the Go field name
+func AbiFieldName(name string, tag reflect.StructTag) string {
+	s := tag.Get("msgpack")
+	if s == "" {
+		s = tag.Get("json")
+	}
+	if i := strings.Index(s, ","); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return name
+	}
+	return s
+}
+
+//AbiStructName returns the abi struct name of the named Go struct type
+//typeName, the lowercased type name
+func AbiStructName(typeName string) string {
+	return strings.ToLower(typeName)
+}
+
+//AbiBasicType returns the abi type of the predeclared Go type named name,
+//or false if it has none. Signed and platform sized integers have none.
+func AbiBasicType(name string) (string, bool) {
+	switch name {
+	case "string", "uint8", "uint16", "uint32", "uint64":
+		return name, true
+	case "byte":
+		return "uint8", true
+	}
+	return "", false
+}
+
+//newAbi returns an empty abi whose lists marshal as [] rather than null
+func newAbi() *ABI {
+	return &ABI{
+		Types:    []ABIType{},
+		Structs:  []ABIStruct{},
+		Variants: []ABIVariant{},
+		Actions:  []ABIAction{},
+		Tables:   []ABITable{},
+	}
+}
@@ -0,0 +1,76 @@
+package msgpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestAbiFromStructs(t *testing.T) {
+	type AccountName string
+
+	type Item struct {
+		Name  string `json:"name"`
+		Value uint64 `json:"value"`
+	}
+
+	type BatchTransfer struct {
+		From   AccountName   `json:"from"`
+		To     []AccountName `json:"to"`
+		Memo   *string       `json:"memo,omitempty"`
+		Items  []Item        `msgpack:"items" json:"item_list"`
+		Extra  *Item         `json:"extra"`
+		Data   []byte        `json:"data"`
+		Amount uint32
+	}
+
+	fmt.Println("TestAbiFromStructs...")
+
+	abi, err := AbiFromStructs(map[string]interface{}{
+		"batchtransfer": &BatchTransfer{},
+		"additem":       Item{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	js, _ := json.Marshal(abi)
+	expect := `{"types":[],"structs":[{"name":"item","base":"","fields":{"name":"string","value":"uint64"}},{"name":"batchtransfer","base":"","fields":{"from":"string","to":"string[]","memo":"string?","items":"item[]","extra":"item?","data":"bytes","Amount":"uint32"}}],"variants":[],"actions":[{"action_name":"additem","type":"item"},{"action_name":"batchtransfer","type":"batchtransfer"}],"tables":[]}`
+	if string(js) != expect {
+		t.Fatalf("AbiFromStructs: %s", js)
+	}
+
+	memo := "memo"
+	bt := BatchTransfer{From: "bottos", To: []AccountName{"a"}, Memo: &memo, Items: []Item{{Name: "x", Value: 1}}, Data: []byte{1}, Amount: 2}
+	b, err := MarshalAbi(bt, abi, "bottos", "batchtransfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bt1 := BatchTransfer{}
+	if err = UnmarshalAbi(b, &bt1, abi, "bottos", "batchtransfer"); err != nil {
+		t.Fatal(err)
+	}
+	if *bt1.Memo != "memo" || bt1.Items[0].Value != 1 || bt1.Amount != 2 || bt1.Extra != nil {
+		t.Fatalf("UnmarshalAbi: %v", bt1)
+	}
+}
+
+func TestAbiFromStructsErrors(t *testing.T) {
+	type unexported struct {
+		name string
+	}
+	type Anonymous struct {
+		Inner struct{ V uint8 }
+	}
+	type Signed struct {
+		V int64
+	}
+
+	fmt.Println("TestAbiFromStructsErrors...")
+
+	for _, v := range []interface{}{"transfer", unexported{}, Anonymous{}, Signed{}} {
+		if _, err := AbiFromStructs(map[string]interface{}{"action": v}); err == nil {
+			t.Fatalf("AbiFromStructs %T: expected error", v)
+		}
+	}
+}
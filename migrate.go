@@ -0,0 +1,218 @@
+package msgpack
+
+import (
+	"fmt"
+	"strings"
+)
+
+//MigratePayload re-encodes data, the params of method encoded with oldAbi,
+//for newAbi. Struct fields are matched by name, recursively: fields removed
+//from newAbi are dropped, and fields added to it take their value from
+//defaults, keyed by field path from the action struct, e.g. "memo" or
+//"to.name". The path of a field inside an array element does not include an
+//index, so its default applies to every element. A new optional field without
+//a default is absent. Unsigned integers may be widened; any other change of
+//type needs a default, which then replaces the old value.
+//
+//Default values have the Go types MarshalAbiEx takes: string, uint8 to uint64,
+//[]byte, map[string]interface{} or *FeildMap for structs, and so on.
+func MigratePayload(data []byte, oldAbi, newAbi *ABI, method string, defaults map[string]interface{}) ([]byte, error) {
+	if oldAbi == nil || newAbi == nil {
+		return nil, fmt.Errorf("MigratePayload: abi is nil")
+	}
+
+	oldType, newType := actionType(oldAbi, method), actionType(newAbi, method)
+	if oldType == "" {
+		return nil, fmt.Errorf("MigratePayload: undefined action %s in old abi", method)
+	}
+	if newType == "" {
+		return nil, fmt.Errorf("MigratePayload: undefined action %s in new abi", method)
+	}
+
+	val, err := UnmarshalAbiEx(data, oldAbi, "", method)
+	if err != nil {
+		return nil, fmt.Errorf("MigratePayload: %v", err)
+	}
+
+	m := &payloadMigrator{old: oldAbi, new: newAbi, defaults: defaults}
+	migrated, err := m.migrate("", oldType, newType, val)
+	if err != nil {
+		return nil, fmt.Errorf("MigratePayload: %v", err)
+	}
+	return MarshalAbiEx(migrated.(*FeildMap).values, newAbi, "", method)
+}
+
+//actionType returns the type of the action named method, or ""
+func actionType(abi *ABI, method string) string {
+	for _, a := range abi.Actions {
+		if a.ActionName == method {
+			return a.Type
+		}
+	}
+	return ""
+}
+
+type payloadMigrator struct {
+	old, new *ABI
+	defaults map[string]interface{}
+}
+
+//migrate converts val, decoded as oldType of the old abi, to newType of the
+//new abi. path names the value in errors and defaults.
+func (m *payloadMigrator) migrate(path string, oldType, newType string, val interface{}) (interface{}, error) {
+	o, err := resolveAbiType(m.old, oldType)
+	if err != nil {
+		return nil, err
+	}
+	n, err := resolveAbiType(m.new, newType)
+	if err != nil {
+		return nil, err
+	}
+
+	if val == nil {
+		if !strings.HasSuffix(n, "?") {
+			return nil, fmt.Errorf("%s: absent value can not be migrated to %s", pathName(path), newType)
+		}
+		return nil, nil
+	}
+	o, n = strings.TrimSuffix(o, "?"), strings.TrimSuffix(n, "?")
+
+	if strings.HasSuffix(o, "[]") || strings.HasSuffix(n, "[]") {
+		if !strings.HasSuffix(o, "[]") || !strings.HasSuffix(n, "[]") {
+			return nil, m.mismatch(path, oldType, newType)
+		}
+		vals := val.([]interface{})
+		out := make([]interface{}, len(vals))
+		for i, v := range vals {
+			if out[i], err = m.migrate(path, strings.TrimSuffix(o, "[]"), strings.TrimSuffix(n, "[]"), v); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	ov, nv := getAbiVariant(m.old, o), getAbiVariant(m.new, n)
+	if ov != nil || nv != nil {
+		if ov == nil || nv == nil {
+			return nil, m.mismatch(path, oldType, newType)
+		}
+		return m.migrateVariant(path, nv, val.(Variant))
+	}
+
+	if ow, ok := uintWidths[o]; ok {
+		nw, ok := uintWidths[n]
+		if !ok || nw < ow {
+			return nil, m.mismatch(path, oldType, newType)
+		}
+		return widenUint(val, n), nil
+	}
+	if o == "string" || o == "bytes" || n == "string" || n == "bytes" || uintWidths[n] != 0 {
+		if o != n {
+			return nil, m.mismatch(path, oldType, newType)
+		}
+		return val, nil
+	}
+
+	return m.migrateStruct(path, o, n, val.(*FeildMap))
+}
+
+func (m *payloadMigrator) migrateVariant(path string, nv *ABIVariant, val Variant) (interface{}, error) {
+	for _, t := range nv.Types {
+		if t == val.Type {
+			v, err := m.migrate(path, val.Type, t, val.Value)
+			if err != nil {
+				return nil, err
+			}
+			return Variant{Type: t, Value: v}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: type %s was removed from variant %s", pathName(path), val.Type, nv.Name)
+}
+
+func (m *payloadMigrator) migrateStruct(path string, o, n string, val *FeildMap) (interface{}, error) {
+	oldFields, err := abiStructFields(m.old, o)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := abiStructFields(m.new, n)
+	if err != nil {
+		return nil, err
+	}
+
+	out := New()
+	for _, f := range newFields.GetStringPair() {
+		fpath := f.Key
+		if path != "" {
+			fpath = path + "." + f.Key
+		}
+		def, hasDefault := m.defaults[fpath]
+
+		oldType, ok := oldFields.GetStringVal(f.Key)
+		if !ok {
+			switch {
+			case hasDefault:
+				out.Set(f.Key, def)
+			case m.optional(f.Value):
+				out.Set(f.Key, nil)
+			default:
+				return nil, fmt.Errorf("%s: no default for new field", fpath)
+			}
+			continue
+		}
+
+		v, _ := val.Get(f.Key)
+		migrated, err := m.migrate(fpath, oldType, f.Value, v)
+		if err != nil {
+			if !hasDefault {
+				return nil, err
+			}
+			migrated = def
+		}
+		out.Set(f.Key, migrated)
+	}
+	return out, nil
+}
+
+//optional reports whether typ of the new abi is an optional type
+func (m *payloadMigrator) optional(typ string) bool {
+	typ, err := resolveAbiType(m.new, typ)
+	return err == nil && strings.HasSuffix(typ, "?")
+}
+
+func (m *payloadMigrator) mismatch(path string, oldType, newType string) error {
+	return fmt.Errorf("%s: %s can not be migrated to %s", pathName(path),
+		describeType(oldType, resolveOrSelf(m.old, oldType)),
+		describeType(newType, resolveOrSelf(m.new, newType)))
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "params"
+	}
+	return path
+}
+
+//widenUint converts the decoded unsigned integer val to the wider typ
+func widenUint(val interface{}, typ string) interface{} {
+	var u uint64
+	switch v := val.(type) {
+	case uint8:
+		u = uint64(v)
+	case uint16:
+		u = uint64(v)
+	case uint32:
+		u = uint64(v)
+	case uint64:
+		u = v
+	}
+
+	switch typ {
+	case "uint8":
+		return uint8(u)
+	case "uint16":
+		return uint16(u)
+	case "uint32":
+		return uint32(u)
+	}
+	return u
+}
@@ -0,0 +1,141 @@
+package msgpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMigratePayload(t *testing.T) {
+	fmt.Println("TestMigratePayload...")
+
+	oldAbi, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "account_name", "type": "string"}],
+		"structs": [
+			{"name": "item", "base": "", "fields": {"name": "string", "value": "uint32"}},
+			{"name": "transfer", "base": "", "fields": {"from": "account_name", "to": "account_name", "value": "uint32", "memo": "string", "items": "item[]", "extra": "item?"}},
+			{"name": "textproposal", "base": "", "fields": {"title": "string"}},
+			{"name": "propose", "base": "", "fields": {"proposal": "proposal"}}
+		],
+		"variants": [{"name": "proposal", "types": ["textproposal", "uint32"]}],
+		"actions": [{"action_name": "transfer", "type": "transfer"}, {"action_name": "propose", "type": "propose"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAbi, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "name", "type": "string"}],
+		"structs": [
+			{"name": "asset", "base": "", "fields": {"name": "string", "tag": "string", "value": "uint64"}},
+			{"name": "transfer", "base": "", "fields": {"from": "name", "to": "name", "value": "uint64", "fee": "uint64", "items": "asset[]", "extra": "asset?", "note": "string?"}},
+			{"name": "textproposal", "base": "", "fields": {"title": "string", "body": "string"}},
+			{"name": "propose", "base": "", "fields": {"proposal": "proposal"}}
+		],
+		"variants": [{"name": "proposal", "types": ["uint32", "textproposal"]}],
+		"actions": [{"action_name": "transfer", "type": "transfer"}, {"action_name": "propose", "type": "propose"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MarshalAbiEx(map[string]interface{}{
+		"from":  "alice",
+		"to":    "bob",
+		"value": uint32(100),
+		"memo":  "dropped",
+		"items": []interface{}{
+			map[string]interface{}{"name": "a", "value": uint32(1)},
+			map[string]interface{}{"name": "b", "value": uint32(2)},
+		},
+		"extra": nil,
+	}, oldAbi, "", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := map[string]interface{}{"fee": uint64(5), "items.tag": "none", "extra.tag": "unused"}
+	migrated, err := MigratePayload(data, oldAbi, newAbi, "transfer", defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err := UnmarshalAbiEx(migrated, newAbi, "", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, _ := json.Marshal(val)
+	want := `{"from":"alice","to":"bob","value":100,"fee":5,"items":[{"name":"a","tag":"none","value":1},{"name":"b","tag":"none","value":2}],"extra":null,"note":null}`
+	if string(js) != want {
+		t.Errorf("transfer:\n got %s\nwant %s", js, want)
+	}
+
+	data, err = MarshalAbiEx(map[string]interface{}{
+		"proposal": Variant{Type: "textproposal", Value: map[string]interface{}{"title": "hi"}},
+	}, oldAbi, "", "propose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, err = MigratePayload(data, oldAbi, newAbi, "propose", map[string]interface{}{"proposal.body": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, err = UnmarshalAbiEx(migrated, newAbi, "", "propose"); err != nil {
+		t.Fatal(err)
+	}
+	js, _ = json.Marshal(val)
+	want = `{"proposal":{"Type":"textproposal","Value":{"title":"hi","body":""}}}`
+	if string(js) != want {
+		t.Errorf("propose:\n got %s\nwant %s", js, want)
+	}
+}
+
+func TestMigratePayloadErrors(t *testing.T) {
+	fmt.Println("TestMigratePayloadErrors...")
+
+	oldAbi, err := ParseAbi([]byte(`{
+		"structs": [{"name": "transfer", "base": "", "fields": {"from": "string", "value": "uint64", "memo": "string?"}}],
+		"actions": [{"action_name": "transfer", "type": "transfer"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalAbiEx(map[string]interface{}{"from": "alice", "value": uint64(1), "memo": nil}, oldAbi, "", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		fields   string
+		defaults map[string]interface{}
+		err      string
+	}{
+		{"new field", `{"from": "string", "value": "uint64", "memo": "string?", "fee": "uint64"}`, nil, "fee: no default for new field"},
+		{"narrowed", `{"from": "string", "value": "uint32", "memo": "string?"}`, nil, "value: uint64 can not be migrated to uint32"},
+		{"optional to required", `{"from": "string", "value": "uint64", "memo": "string"}`, nil, "memo: absent value can not be migrated to string"},
+		{"default replaces", `{"from": "string", "value": "string", "memo": "string"}`, map[string]interface{}{"value": "one", "memo": ""}, ""},
+	}
+	for _, c := range cases {
+		newAbi, err := ParseAbi([]byte(`{
+			"structs": [{"name": "transfer", "base": "", "fields": ` + c.fields + `}],
+			"actions": [{"action_name": "transfer", "type": "transfer"}]
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = MigratePayload(data, oldAbi, newAbi, "transfer", c.defaults)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: got error %v, want %q", c.name, err, c.err)
+		}
+	}
+
+	if _, err := MigratePayload(data, oldAbi, &ABI{}, "transfer", nil); err == nil {
+		t.Error("missing action in new abi: no error")
+	}
+}
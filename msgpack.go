@@ -27,11 +27,21 @@ package msgpack
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"reflect"
+	"strconv"
 )
 
+//RawMessage is a raw encoded msgpack value. Decode captures one complete
+//value into it verbatim and Encode writes it back verbatim, so it can be used
+//to delay decoding or to precompute an encoding. An empty RawMessage is
+//encoded as nil.
+type RawMessage []byte
+
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
+
 //Marshal is to serialize the message
 func Marshal(v interface{}) ([]byte, error) {
 	writer := &bytes.Buffer{}
@@ -49,6 +59,13 @@ func Unmarshal(data []byte, dst interface{}) error {
 	return err
 }
 
+//UnmarshalLenient is to unserialize the message in lenient mode, see Decoder.SetLenient
+func UnmarshalLenient(data []byte, dst interface{}) error {
+	d := NewDecoder(bytes.NewReader(data))
+	d.SetLenient(true)
+	return d.Decode(dst)
+}
+
 //Encode is to encode message
 func Encode(w io.Writer, structs interface{}) error {
 	v := reflect.ValueOf(structs)
@@ -66,6 +83,10 @@ func Encode(w io.Writer, structs interface{}) error {
 		}
 	}
 
+	if v.Type() == rawMessageType {
+		return encodeRaw(w, v.Bytes())
+	}
+
 	values := make([]interface{}, v.NumField())
     for i := 0; i < v.NumField(); i++ {
         values[i] = v.Field(i).Interface()
@@ -76,6 +97,13 @@ func Encode(w io.Writer, structs interface{}) error {
 		t := reflect.TypeOf(values[i])
 		val := reflect.ValueOf(values[i])
 
+		if t == rawMessageType {
+			if err := encodeRaw(w, val.Bytes()); err != nil {
+				return err
+			}
+			continue
+		}
+
 		kind := t.Kind()
         switch kind {
         case reflect.String:
@@ -110,8 +138,52 @@ func Encode(w io.Writer, structs interface{}) error {
 	return nil
 }
 
-//Decode is to encode message
-func Decode(r io.Reader, dst interface{}) error {
+func encodeRaw(w io.Writer, raw []byte) error {
+	if len(raw) == 0 {
+		raw = Bytes{NIL}
+	}
+	_, err := w.Write(raw)
+	return err
+}
+
+//Decoder reads and decodes msgpack values from an input stream
+type Decoder struct {
+	r       io.Reader
+	lenient bool
+}
+
+//NewDecoder returns a new decoder that reads from r. The decoder reads no
+//further than the values it decodes, so r may be shared with other readers.
+//PeekType needs r to be an io.ByteScanner, such as a bufio.Reader.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+//SetLenient switches the decoder to lenient mode. In lenient mode the array
+//size written by the encoder is honoured: extra trailing elements are skipped
+//and missing ones leave the remaining fields at their zero value, or at the
+//value declared by a `default:"..."` struct tag.
+func (d *Decoder) SetLenient(lenient bool) {
+	d.lenient = lenient
+}
+
+//Skip consumes the next value without decoding it
+func (d *Decoder) Skip() error {
+	return Skip(d.r)
+}
+
+//PeekType reports the type family of the next value without consuming it.
+//It fails if the reader of d is not an io.ByteScanner.
+func (d *Decoder) PeekType() (Type, error) {
+	sr, ok := d.r.(io.ByteScanner)
+	if !ok {
+		return InvalidType, fmt.Errorf("PeekType: %T is not an io.ByteScanner", d.r)
+	}
+	return PeekType(sr)
+}
+
+//Decode is to decode the next message into dst
+func (d *Decoder) Decode(dst interface{}) error {
 	v := reflect.ValueOf(dst)
 
 	if !v.IsValid() {
@@ -129,73 +201,150 @@ func Decode(r io.Reader, dst interface{}) error {
 		return fmt.Errorf("Nil Ptr: %T\n", dst)
 	}
 
-	if v.Elem().NumField() > 0 {
-		UnpackArraySize(r)
+	if v.Elem().Type() == rawMessageType {
+		return d.decodeRaw(v.Elem())
 	}
 
-	for i := 0; i < v.Elem().NumField(); i++ {
-		feild := v.Elem().Field(i)
-		feildAddr := feild.Addr().Interface()
-        switch feild.Kind() {
-        case reflect.String:
-			val, err := UnpackStr16(r)
-			if err != nil {
-				return err
-			}
-			ptr :=feildAddr.(*string)
-  			*ptr = val
-        case reflect.Uint8:
-			val, err := UnpackUint8(r)
-			if err != nil {
-				return err
-			}
-			ptr := feildAddr.(*uint8)
-  			*ptr = val
-		case reflect.Uint16:
-			val, err := UnpackUint16(r)
-			if err != nil {
-				return err
-			}
-			ptr := feildAddr.(*uint16)
-  			*ptr = val
-		case reflect.Uint32:
-			val, err := UnpackUint32(r)
-			if err != nil {
-				return err
-			}
-			ptr := feildAddr.(*uint32)
-  			*ptr = val
-		case reflect.Uint64:
-			val, err := UnpackUint64(r)
-			if err != nil {
+	return d.decodeStruct(v.Elem())
+}
+
+func (d *Decoder) decodeRaw(v reflect.Value) error {
+	buf := &bytes.Buffer{}
+	if err := skipValue(d.r, buf); err != nil {
+		return err
+	}
+	v.SetBytes(buf.Bytes())
+	return nil
+}
+
+func (d *Decoder) decodeStruct(v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("Unsupported Type: %v", v.Type())
+	}
+
+	size, err := UnpackArraySize(d.r)
+	if err != nil {
+		return err
+	}
+
+	count := v.NumField()
+	for i := 0; i < count; i++ {
+		if d.lenient && i >= int(size) {
+			err = setDefault(v.Field(i), v.Type().Field(i))
+		} else {
+			err = d.decodeField(v.Field(i))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.lenient {
+		for i := count; i < int(size); i++ {
+			if err := d.Skip(); err != nil {
 				return err
 			}
-			ptr := feildAddr.(*uint64)
-  			*ptr = val
-		case reflect.Slice:
-			if feild.Type().Elem().Kind() == reflect.Uint8 {
-				val, err := UnpackBin16(r)
-				if err != nil {
-					return err
-				}
-				ptr := feildAddr.(*[]byte)
-				*ptr = val
-			} else {
-				return fmt.Errorf("Unsupported Slice Type")
-			}
-		case reflect.Struct:
-			vv := feild.Interface()
-			if reflect.ValueOf(vv).Kind() != reflect.Ptr {
-				vv = feildAddr
-			}
-			Decode(r, vv)
-		case reflect.Ptr:
-			vv := feild.Interface()
-			Decode(r, vv)
-		default:
-			return fmt.Errorf("Unsupported Type")
 		}
 	}
 
 	return nil
 }
+
+func (d *Decoder) decodeField(feild reflect.Value) error {
+	if feild.Type() == rawMessageType {
+		return d.decodeRaw(feild)
+	}
+
+	switch feild.Kind() {
+	case reflect.String:
+		val, err := UnpackStr16(d.r)
+		if err != nil {
+			return err
+		}
+		feild.SetString(val)
+	case reflect.Uint8:
+		val, err := UnpackUint8(d.r)
+		if err != nil {
+			return err
+		}
+		feild.SetUint(uint64(val))
+	case reflect.Uint16:
+		val, err := UnpackUint16(d.r)
+		if err != nil {
+			return err
+		}
+		feild.SetUint(uint64(val))
+	case reflect.Uint32:
+		val, err := UnpackUint32(d.r)
+		if err != nil {
+			return err
+		}
+		feild.SetUint(uint64(val))
+	case reflect.Uint64:
+		val, err := UnpackUint64(d.r)
+		if err != nil {
+			return err
+		}
+		feild.SetUint(val)
+	case reflect.Slice:
+		if feild.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("Unsupported Slice Type")
+		}
+		val, err := UnpackBin16(d.r)
+		if err != nil {
+			return err
+		}
+		feild.SetBytes(val)
+	case reflect.Struct:
+		return d.decodeStruct(feild)
+	case reflect.Ptr:
+		if feild.IsNil() {
+			feild.Set(reflect.New(feild.Type().Elem()))
+		}
+		return d.decodeStruct(feild.Elem())
+	default:
+		return fmt.Errorf("Unsupported Type")
+	}
+
+	return nil
+}
+
+//setDefault resets a field that is missing from the payload, applying the
+//value of its `default` tag if there is one. Bytes defaults are hex encoded.
+func setDefault(feild reflect.Value, sf reflect.StructField) error {
+	feild.Set(reflect.Zero(feild.Type()))
+
+	def, ok := sf.Tag.Lookup("default")
+	if !ok {
+		return nil
+	}
+
+	switch feild.Kind() {
+	case reflect.String:
+		feild.SetString(def)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		val, err := strconv.ParseUint(def, 0, feild.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Invalid default for %s: %v", sf.Name, err)
+		}
+		feild.SetUint(val)
+	case reflect.Slice:
+		if feild.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("Unsupported Slice Type")
+		}
+		val, err := hex.DecodeString(def)
+		if err != nil {
+			return fmt.Errorf("Invalid default for %s: %v", sf.Name, err)
+		}
+		feild.SetBytes(val)
+	default:
+		return fmt.Errorf("Unsupported default for %s: %v", sf.Name, feild.Kind())
+	}
+
+	return nil
+}
+
+//Decode is to decode message
+func Decode(r io.Reader, dst interface{}) error {
+	return NewDecoder(r).Decode(dst)
+}
@@ -30,6 +30,7 @@ import (
 	"reflect"
 	"bytes"
 	"io"
+	"strings"
 )
 
 const (
@@ -81,6 +82,12 @@ type ABIAction struct {
 	Type       string `json:"type"`
 }
 
+//ABIType type alias for abi, e.g. account_name -> string
+type ABIType struct {
+	NewTypeName string `json:"new_type_name"`
+	Type        string `json:"type"`
+}
+
 //ABIStruct parameter struct for abi Action(Method)
 type ABIStruct struct {
 	Name   string    `json:"name"`
@@ -88,14 +95,40 @@ type ABIStruct struct {
 	Fields *FeildMap `json:"fields"`
 }
 
+//ABIVariant tagged union of abi types, encoded as [type_index, value]
+type ABIVariant struct {
+	Name  string   `json:"name"`
+	Types []string `json:"types"`
+}
+
+//ABITable contract storage table, rows are encoded as abi struct Type and
+//keys as the concatenation of KeyTypes
+type ABITable struct {
+	Name      string   `json:"table_name"`
+	IndexType string   `json:"index_type"`
+	KeyNames  []string `json:"key_names"`
+	KeyTypes  []string `json:"key_types"`
+	Type      string   `json:"type"`
+}
+
 //ABI struct for abi
 type ABI struct {
-	Types   []interface{} `json:"types"`
-	Structs []ABIStruct   `json:"structs"`
-	Actions []ABIAction   `json:"actions"`
-	Tables  []interface{} `json:"tables"`
+	Types    []ABIType     `json:"types"`
+	Structs  []ABIStruct   `json:"structs"`
+	Variants []ABIVariant  `json:"variants"`
+	Actions  []ABIAction   `json:"actions"`
+	Tables   []ABITable    `json:"tables"`
+}
+
+//Variant is the value of an abi variant type, Type is one of the types
+//declared by the variant. Decoded struct values are *FeildMap.
+type Variant struct {
+	Type  string
+	Value interface{}
 }
 
+var variantType = reflect.TypeOf(Variant{})
+
 //ABIStructs structs for ABI
 type ABIStructs struct {
 	Structs []struct {
@@ -125,6 +158,9 @@ func PackUint64(writer io.Writer, value uint64) (n int, err error) {
 	return writer.Write(Bytes{UINT64, byte(value >> 56), byte(value >> 48), byte(value >> 40), byte(value >> 32), byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)})
 }
 
+//uintWidths maps the uint abi types to their width in bits
+var uintWidths = map[string]int{"uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64}
+
 //PackBin16 is to pack a given value and writes it into the specified writer.
 func PackBin16(writer io.Writer, value []byte) (n int, err error) {
 	length := len(value)
@@ -147,6 +183,11 @@ func PackStr16(writer io.Writer, value string) (n int, err error) {
 	return n1 + n2, err
 }
 
+//PackNil is to pack nil and writes it into the specified writer.
+func PackNil(writer io.Writer) (n int, err error) {
+	return writer.Write(Bytes{NIL})
+}
+
 //PackArraySize is to pack a given value and writes it into the specified writer.
 func PackArraySize(writer io.Writer, length uint16) (n int, err error) {
 	n, err = writer.Write(Bytes{ARRAY16, byte(length >> 8), byte(length)})
@@ -156,20 +197,25 @@ func PackArraySize(writer io.Writer, length uint16) (n int, err error) {
 	return n, nil
 }
 
-//MarshalAbi is to serialize the message
-func MarshalAbi(v interface{}, Abi *ABI, contractName string, method string) ([]byte, error) {
-	var err error
-	var abi ABI
+//packArrayLen writes the array header of n items, which can hold at most
+//0xffff items
+func packArrayLen(w io.Writer, n int) error {
+	if n > 0xffff {
+		return fmt.Errorf("array of %d items exceeds 65535", n)
+	}
+	_, err := PackArraySize(w, uint16(n))
+	return err
+}
 
+//MarshalAbi is to serialize the message. Abi is only read, so an abi shared
+//through an AbiRegistry can be used concurrently.
+func MarshalAbi(v interface{}, Abi *ABI, contractName string, method string) ([]byte, error) {
 	if Abi == nil {
-		return []byte{}, err
+		return []byte{}, fmt.Errorf("MarshalAbi: abi is nil")
 	}
-	
-	abi = *Abi
-	
 
 	writer := &bytes.Buffer{}
-	err = EncodeAbi(contractName, method, writer, v, abi, "")
+	err := encodeAbi(contractName, method, writer, v, Abi, "")
 	if err != nil {
 		return []byte{}, err
 	}
@@ -177,35 +223,22 @@ func MarshalAbi(v interface{}, Abi *ABI, contractName string, method string) ([]
 }
 
 
-func getAbiFieldsByAbi(contractname string, method string, abi ABI, subStructName string) map[string]interface{} {
-	for _, subaction := range abi.Actions {
-		if subaction.ActionName != method {
-			continue
-		}
-
-		structname := subaction.Type
-
-		for _, substruct := range abi.Structs {
-			if subStructName != "" {
-				if substruct.Name != subStructName {
-					continue
-				}
-			} else if structname != substruct.Name {
-				continue
-			}
-
-			return substruct.Fields.values
-		}
-	}
-
-	return nil
+//abiFieldName returns the abi field name of a struct field, see AbiFieldName
+func abiFieldName(sf reflect.StructField) string {
+	return AbiFieldName(sf.Name, sf.Tag)
 }
 
-//EncodeAbi is to encode message
+//EncodeAbi is to encode message. Fields are written in abi order, every abi
+//field must have a struct field and every struct field an abi field.
 func EncodeAbi(contractName string, method string, w io.Writer, value interface{}, abi ABI, subStructName string) error {
-	abiFields := getAbiFieldsByAbi(contractName, method, abi, subStructName)
-	if abiFields == nil {
-		return fmt.Errorf("EncodeAbi: getAbiFieldsByAbi failed: %s", abi)
+	return encodeAbi(contractName, method, w, value, &abi, subStructName)
+}
+
+//encodeAbi is EncodeAbi without copying the abi
+func encodeAbi(contractName string, method string, w io.Writer, value interface{}, abi *ABI, subStructName string) error {
+	abiFields, err := getAbiFieldsByAbiEx(contractName, method, abi, subStructName)
+	if err != nil {
+		return fmt.Errorf("EncodeAbi: getAbiFieldsByAbi failed: %v", err)
 	}
 
 	v := reflect.ValueOf(value)
@@ -223,23 +256,99 @@ func EncodeAbi(contractName string, method string, w io.Writer, value interface{
 		}
 	}
 
-	count := v.NumField()
-	PackArraySize(w, uint16(count))
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("Unsupported Type: %T", value)
+	}
 
-	for i := 0; i < count; i++ {
-		fieldname := vt.Field(i).Tag.Get("json")
-		vals := v.Field(i).Interface()
+	index := map[string]int{}
+	for i := 0; i < v.NumField(); i++ {
+		fieldname := abiFieldName(vt.Field(i))
+		if _, ok := abiFields.Get(fieldname); !ok {
+			return fmt.Errorf("%s is not in abiFields %v!", fieldname, abiFields.Keys())
+		}
+		index[fieldname] = i
+	}
+
+	pairs := abiFields.GetStringPair()
+	PackArraySize(w, uint16(len(pairs)))
+
+	for _, pair := range pairs {
+		i, ok := index[pair.Key]
+		if !ok {
+			return fmt.Errorf("EncodeAbi: abi field %s not found in %v", pair.Key, vt)
+		}
+
+		err = encodeAbiValue(contractName, method, w, abi, pair.Value, v.Field(i))
+		if err != nil {
+			return fmt.Errorf("EncodeAbi: field %s: %v", pair.Key, err)
+		}
+	}
+
+	return nil
+}
+
+//encodeAbiValue encodes val as abiType. Array types `T[]` take a slice, and
+//optional types `T?` take a pointer or interface that may be nil.
+func encodeAbiValue(contractName string, method string, w io.Writer, abi *ABI, abiType string, val reflect.Value) error {
+	abiType, err := resolveAbiType(abi, abiType)
+	if err != nil {
+		return err
+	}
+
+	if val.Kind() == reflect.Interface && !val.IsNil() {
+		val = val.Elem()
+	}
 
-		types := reflect.TypeOf(vals)
-		val := reflect.ValueOf(vals)
+	switch {
+	case strings.HasSuffix(abiType, "?"):
+		if (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) && val.IsNil() {
+			_, err = PackNil(w)
+			return err
+		}
+		return encodeAbiValue(contractName, method, w, abi, strings.TrimSuffix(abiType, "?"), val)
+	case strings.HasSuffix(abiType, "[]"):
+		if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+			return fmt.Errorf("abiType %s mismatch to %v", abiType, val.Type())
+		}
+		if err = packArrayLen(w, val.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < val.Len(); i++ {
+			err = encodeAbiValue(contractName, method, w, abi, strings.TrimSuffix(abiType, "[]"), val.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
-		if _, ok := abiFields[fieldname]; !ok {
-			return fmt.Errorf("%s is not in abiFields [%s]!", fieldname, abiFields)
+	if variant := getAbiVariant(abi, abiType); variant != nil {
+		return encodeAbiVariant(contractName, method, w, abi, variant, val.Interface())
+	}
+
+	if val.Kind() == reflect.Ptr && !isAbiStructType(abiType) {
+		if val.IsNil() {
+			return fmt.Errorf("Nil Ptr: %v", val.Type())
 		}
+		val = val.Elem()
+	}
 
-		switch abiFields[fieldname] {
-		case "string":
-			PackStr16(w, val.String())
+	switch abiType {
+	case "string":
+		if val.Kind() != reflect.String {
+			return fmt.Errorf("abiType %s mismatch to %v", abiType, val.Type())
+		}
+		PackStr16(w, val.String())
+	case "uint8", "uint16", "uint32", "uint64":
+		switch val.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		default:
+			return fmt.Errorf("abiType %s mismatch to %v", abiType, val.Type())
+		}
+		if bits := uintWidths[abiType]; bits < 64 && val.Uint()>>uint(bits) != 0 {
+			return fmt.Errorf("%d overflows %s", val.Uint(), abiType)
+		}
+		switch abiType {
 		case "uint8":
 			PackUint8(w, uint8(val.Uint()))
 		case "uint16":
@@ -248,118 +357,226 @@ func EncodeAbi(contractName string, method string, w io.Writer, value interface{
 			PackUint32(w, uint32(val.Uint()))
 		case "uint64":
 			PackUint64(w, uint64(val.Uint()))
-		case "bytes":
-			t := reflect.TypeOf(v.Field(i).Interface())
-			if t.Elem().Kind() == reflect.Uint8 {
-				PackBin16(w, val.Bytes())
-			} else {
-				return fmt.Errorf("Unsupported Slice Type")
-			}
-		default:
-			t := reflect.TypeOf(v.Field(i).Interface())
-			if t.Kind() == reflect.Struct || t.Kind() == reflect.Ptr {
-				EncodeAbi(contractName, method, w, v.Field(i).Interface(), abi, fieldname)
-			} else {
-				return fmt.Errorf("Unsupported Type: %v", types)
-			}
 		}
+	case "bytes":
+		if val.Kind() == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8 {
+			PackBin16(w, val.Bytes())
+		} else {
+			return fmt.Errorf("Unsupported Slice Type")
+		}
+	default:
+		if _, err := getAbiFieldsByAbiEx(contractName, method, abi, abiType); err != nil {
+			return err
+		}
+
+		if val.Kind() == reflect.Struct || val.Kind() == reflect.Ptr {
+			return encodeAbi(contractName, method, w, val.Interface(), abi, abiType)
+		}
+		return fmt.Errorf("Unsupported Type: %v", val.Type())
 	}
 
 	return nil
 }
 
-//getAbiFieldsByAbiEx function
-func getAbiFieldsByAbiEx(contractname string, method string, abi ABI, subStructName string) *FeildMap {
-	for _, subaction := range abi.Actions {
-		if subaction.ActionName != method {
-			continue
+//encodeAbiVariant encodes a Variant or *Variant as [type_index, value]
+func encodeAbiVariant(contractName string, method string, w io.Writer, abi *ABI, variant *ABIVariant, val interface{}) error {
+	var vv Variant
+	switch v := val.(type) {
+	case Variant:
+		vv = v
+	case *Variant:
+		if v == nil {
+			return fmt.Errorf("Nil Ptr: %T", val)
 		}
-		structname := subaction.Type
-
-		for _, substruct := range abi.Structs {
-			if subStructName != "" {
-				if substruct.Name != subStructName {
-					continue
-				}
-			} else if structname != substruct.Name {
-				continue
-			}
+		vv = *v
+	default:
+		return fmt.Errorf("variant %s mismatch to %T", variant.Name, val)
+	}
 
-			return substruct.Fields
+	index := -1
+	for i, typ := range variant.Types {
+		if typ == vv.Type {
+			index = i
+			break
 		}
 	}
+	if index < 0 || index >= REGULAR_UINT8_MAX {
+		return fmt.Errorf("type %s is not in variant %s %v", vv.Type, variant.Name, variant.Types)
+	}
 
-	return nil
+	PackArraySize(w, 2)
+	PackUint8(w, uint8(index))
+	return encodeAbiValueEx(contractName, method, w, abi, vv.Type, vv.Value)
+}
+
+//isAbiStructType reports whether a resolved abi type names a struct rather
+//than a primitive
+func isAbiStructType(abiType string) bool {
+	switch abiType {
+	case "string", "uint8", "uint16", "uint32", "uint64", "bytes":
+		return false
+	}
+	return !strings.HasSuffix(abiType, "[]") && !strings.HasSuffix(abiType, "?")
+}
+
+//getAbiFieldsByAbiEx returns the fields of the abi struct named subStructName,
+//or of the parameter struct of method if subStructName is empty. Fields of
+//base structs come first.
+func getAbiFieldsByAbiEx(contractname string, method string, abi *ABI, subStructName string) (*FeildMap, error) {
+	structname := subStructName
+	if structname == "" {
+		for _, subaction := range abi.Actions {
+			if subaction.ActionName == method {
+				structname = subaction.Type
+				break
+			}
+		}
+		if structname == "" {
+			return nil, fmt.Errorf("undefined action %s", method)
+		}
+	}
+
+	structname, err := resolveAbiType(abi, structname)
+	if err != nil {
+		return nil, err
+	}
+
+	return abiStructFields(abi, structname)
 }
 
 //EncodeAbiEx is to encode message
 func EncodeAbiEx(contractName string, method string, w io.Writer, value map[string]interface{}, abi ABI, subStructName string) error {
-        abiFieldsAttr := getAbiFieldsByAbiEx(contractName, method, abi, subStructName)
-	if abiFieldsAttr == nil {
-		return fmt.Errorf("EncodeAbiEx: getAbiFieldsByAbi failed: %s", abi)
+	return encodeAbiEx(contractName, method, w, value, &abi, subStructName)
+}
 
+//encodeAbiEx is EncodeAbiEx without copying the abi
+func encodeAbiEx(contractName string, method string, w io.Writer, value map[string]interface{}, abi *ABI, subStructName string) error {
+	abiFieldsAttr, err := getAbiFieldsByAbiEx(contractName, method, abi, subStructName)
+	if err != nil {
+		return fmt.Errorf("EncodeAbiEx: getAbiFieldsByAbi failed: %v", err)
 	}
 
 	abiFields := abiFieldsAttr.GetStringPair()
-	
-	count  := len(abiFields)
+
+	count := len(abiFields)
 	count2 := len(value)
-	
+
 	if count != count2 {
 		return fmt.Errorf("EncodeAbiEx: fields number mismatch! count: %d, count2: %d", count, count2)
 	}
-	
-	if (count <= 0) {
-		return fmt.Errorf("EncodeAbiEx: count is 0!", count)
+
+	if count <= 0 {
+		return fmt.Errorf("EncodeAbiEx: count is 0!")
 	}
 
 	PackArraySize(w, uint16(count))
 
-		for _, abiValTypeAttr := range abiFields {
-			
-			abiValKey   := abiValTypeAttr.Key
-			abiValType := abiValTypeAttr.Value
+	for _, abiValTypeAttr := range abiFields {
+		abiValKey := abiValTypeAttr.Key
 
-			val, ok := value[abiValKey]
-			
-			if !ok {
-				return fmt.Errorf("EncodeAbiEx: value abiValKey %s not found in map", abiValKey)
-			}
-			
-			valType := reflect.TypeOf(val).Name()
-			
-			if reflect.ValueOf(val).Kind() == reflect.Slice {
-				valType = reflect.TypeOf(val).Elem().Name()
-				if valType == "uint8"	{
-					valType = "bytes"
-				}
+		val, ok := value[abiValKey]
+		if !ok {
+			return fmt.Errorf("EncodeAbiEx: value abiValKey %s not found in map", abiValKey)
+		}
+
+		err = encodeAbiValueEx(contractName, method, w, abi, abiValTypeAttr.Value, val)
+		if err != nil {
+			return fmt.Errorf("EncodeAbiEx: field %s: %v", abiValKey, err)
+		}
+	}
+
+	return nil
+}
+
+//encodeAbiValueEx encodes val as abiType. Array types `T[]` take a
+//[]interface{} or any other slice, and optional types `T?` take nil or a value.
+func encodeAbiValueEx(contractName string, method string, w io.Writer, abi *ABI, abiType string, val interface{}) error {
+	abiType, err := resolveAbiType(abi, abiType)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasSuffix(abiType, "?"):
+		if val == nil {
+			_, err = PackNil(w)
+			return err
+		}
+		return encodeAbiValueEx(contractName, method, w, abi, strings.TrimSuffix(abiType, "?"), val)
+	case strings.HasSuffix(abiType, "[]"):
+		rv := reflect.ValueOf(val)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fmt.Errorf("abiValType %s mismatch to valType %T", abiType, val)
+		}
+		if err = packArrayLen(w, rv.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			err = encodeAbiValueEx(contractName, method, w, abi, strings.TrimSuffix(abiType, "[]"), rv.Index(i).Interface())
+			if err != nil {
+				return err
 			}
-				
-			if valType != abiValType {
-				return fmt.Errorf("EncodeAbiEx: abiValType %s mismatch to valType %s", abiValType, valType)
+		}
+		return nil
+	}
+
+	if val == nil {
+		return fmt.Errorf("value of abiValType %s is nil", abiType)
+	}
+
+	if variant := getAbiVariant(abi, abiType); variant != nil {
+		return encodeAbiVariant(contractName, method, w, abi, variant, val)
+	}
+
+	switch abiType {
+	case "string", "uint8", "uint16", "uint32", "uint64", "bytes":
+		valType := reflect.TypeOf(val).Name()
+
+		if reflect.ValueOf(val).Kind() == reflect.Slice {
+			valType = reflect.TypeOf(val).Elem().Name()
+			if valType == "uint8" {
+				valType = "bytes"
 			}
+		}
 
-			switch abiValType {
-				case "string":
-					PackStr16(w, val.(string))
-				case "uint8":
-					PackUint8(w, val.(uint8))
-				case "uint16":
-					PackUint16(w, val.(uint16))
-				case "uint32":
-					PackUint32(w, val.(uint32))
-				case "uint64":
-					PackUint64(w, val.(uint64))
-				case "bytes":
-					PackBin16(w, val.([]byte))
-				default:
-					if reflect.ValueOf(value[abiValKey]).Kind() == reflect.Struct {
-						EncodeAbi(contractName, method, w, value[abiValKey], abi, abiValKey)
-					} else {
-						return fmt.Errorf("Unsupported Type: %v | %v", valType, abiValType)
-					}
-				}
+		if valType != abiType {
+			return fmt.Errorf("abiValType %s mismatch to valType %s", abiType, valType)
 		}
+	}
+
+	switch abiType {
+	case "string":
+		PackStr16(w, val.(string))
+	case "uint8":
+		PackUint8(w, val.(uint8))
+	case "uint16":
+		PackUint16(w, val.(uint16))
+	case "uint32":
+		PackUint32(w, val.(uint32))
+	case "uint64":
+		PackUint64(w, val.(uint64))
+	case "bytes":
+		PackBin16(w, val.([]byte))
+	default:
+		if _, err := getAbiFieldsByAbiEx(contractName, method, abi, abiType); err != nil {
+			return err
+		}
+
+		switch sub := val.(type) {
+		case map[string]interface{}:
+			return encodeAbiEx(contractName, method, w, sub, abi, abiType)
+		case *FeildMap:
+			return encodeAbiEx(contractName, method, w, sub.values, abi, abiType)
+		case FeildMap:
+			return encodeAbiEx(contractName, method, w, sub.values, abi, abiType)
+		}
+
+		kind := reflect.ValueOf(val).Kind()
+		if kind != reflect.Struct && kind != reflect.Ptr {
+			return fmt.Errorf("Unsupported Type: %T | %v", val, abiType)
+		}
+		return encodeAbi(contractName, method, w, val, abi, abiType)
+	}
 
 	return nil
 }
@@ -368,20 +585,15 @@ func Setmapval(structmap map[string]interface{}, key string, val interface{}) {
         structmap[key] = val
 }
 
-//MarshalAbiEx is to serialize the message
+//MarshalAbiEx is to serialize the message. Abi is only read, so an abi shared
+//through an AbiRegistry can be used concurrently.
 func MarshalAbiEx(v map[string]interface{}, Abi *ABI, contractName string, method string) ([]byte, error) {
-	var err error
-	var abi ABI
-	
-	
 	if Abi == nil {
-		return []byte{}, err
+		return []byte{}, fmt.Errorf("MarshalAbiEx: abi is nil")
 	}
-	
-	abi = *Abi
 
 	writer := &bytes.Buffer{}
-	err = EncodeAbiEx(contractName, method, writer, v, abi, "")
+	err := encodeAbiEx(contractName, method, writer, v, Abi, "")
 	if err != nil {
 		return []byte{}, err
 	}
@@ -26,8 +26,9 @@ package msgpack
 
 import (
 	"fmt"
-	//"bytes"
+	"bytes"
 	"encoding/hex"
+	"io"
 	"testing"
 )
 
@@ -467,3 +468,225 @@ func TestDataDeal_BuyAssetReq(t *testing.T) {
 	err = Unmarshal(b, &ts1)
 	fmt.Println("ts1 ", ts1, err)
 }
+
+func TestUnmarshalLenient(t *testing.T) {
+	type TransferV1 struct {
+		From  string
+		To    string
+		Value uint64
+	}
+
+	type TransferV2 struct {
+		From  string
+		To    string
+		Value uint64
+		Memo  string `default:"none"`
+		Fee   uint32 `default:"10"`
+		Extra []byte `default:"0102"`
+	}
+
+	fmt.Println("TestUnmarshalLenient...")
+
+	// old payload, new struct
+	b, _ := Marshal(TransferV1{From: "bottos", To: "bot", Value: 100})
+	v2 := TransferV2{Memo: "stale"}
+	err := UnmarshalLenient(b, &v2)
+	if err != nil || v2.Value != 100 || v2.Memo != "none" || v2.Fee != 10 || BytesToHex(v2.Extra) != "0102" {
+		t.Fatalf("backward decode failed: %v %v", v2, err)
+	}
+
+	// new payload, old struct, followed by another value
+	b, _ = Marshal(TransferV2{From: "bottos", To: "bot", Value: 100, Memo: "memo", Fee: 1})
+	tail, _ := Marshal(TransferV1{From: "a", To: "b", Value: 1})
+	d := NewDecoder(bytes.NewReader(append(b, tail...)))
+	d.SetLenient(true)
+	v1 := TransferV1{}
+	err = d.Decode(&v1)
+	if err != nil || v1.From != "bottos" || v1.Value != 100 {
+		t.Fatalf("forward decode failed: %v %v", v1, err)
+	}
+	err = d.Decode(&v1)
+	if err != nil || v1.From != "a" || v1.Value != 1 {
+		t.Fatalf("decode after skipped fields failed: %v %v", v1, err)
+	}
+}
+
+func TestSkipPeekType(t *testing.T) {
+	fmt.Println("TestSkipPeekType...")
+
+	// [array16(3) [str16 "testuser", uint32 99, array16(2) [str16 "123", uint32 3]]],
+	// fixmap {fixstr "k": [nil, true, -1]}, uint8 7
+	b, _ := HexToBytes("dc0003da00087465737475736572ce00000063dc0002da0003313233ce00000003" +
+		"81a16b93c0c3ff" + "cc07")
+	d := NewDecoder(bytes.NewReader(b))
+
+	expect := []Type{ArrayType, MapType, UintType}
+	for _, e := range expect {
+		typ, err := d.PeekType()
+		if err != nil || typ != e {
+			t.Fatalf("PeekType: got %v %v, want %v", typ, err, e)
+		}
+		if err = d.Skip(); err != nil {
+			t.Fatalf("Skip %v: %v", e, err)
+		}
+	}
+	if _, err := d.PeekType(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	if err := Skip(bytes.NewReader(b[:10])); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+	}
+
+	if _, err := NewDecoder(io.MultiReader(bytes.NewReader(b))).PeekType(); err == nil {
+		t.Fatal("expected PeekType to fail without an io.ByteScanner")
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	type Msg struct {
+		V1 string
+	}
+
+	fmt.Println("TestDecodeStream...")
+
+	first, _ := Marshal(Msg{V1: "first"})
+	second, _ := Marshal(Msg{V1: "second"})
+	// MultiReader is not an io.ByteScanner, like a net.Conn or a pipe
+	r := io.MultiReader(bytes.NewReader(append(first, second...)))
+
+	var m Msg
+	if err := Decode(r, &m); err != nil || m.V1 != "first" {
+		t.Fatalf("first Decode: %v %v", m, err)
+	}
+	if err := Decode(r, &m); err != nil || m.V1 != "second" {
+		t.Fatalf("second Decode: %v %v", m, err)
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	type Transfer struct {
+		From  string
+		To    string
+		Value uint64
+	}
+
+	type Envelope struct {
+		Contract string
+		Param    RawMessage
+		Sequence uint32
+	}
+
+	fmt.Println("TestRawMessage...")
+
+	param, _ := Marshal(Transfer{From: "bottos", To: "bot", Value: 100})
+	b, err := Marshal(Envelope{Contract: "bottos", Param: param, Sequence: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := Envelope{}
+	err = Unmarshal(b, &env)
+	if err != nil || env.Sequence != 7 || BytesToHex(env.Param) != BytesToHex(param) {
+		t.Fatalf("decode envelope failed: %v %v", env, err)
+	}
+
+	ts := Transfer{}
+	err = Unmarshal(env.Param, &ts)
+	if err != nil || ts.Value != 100 {
+		t.Fatalf("decode param failed: %v %v", ts, err)
+	}
+
+	b1, _ := Marshal(env)
+	if BytesToHex(b1) != BytesToHex(b) {
+		t.Fatalf("re-encode mismatch: %x != %x", b1, b)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	fmt.Println("TestExtract...")
+
+	b, _ := HexToBytes("dc0003da00087465737475736572ce00000063dc0002da0003313233ce00000003")
+
+	v, err := ExtractUint64(b, 1)
+	if err != nil || v != 99 {
+		t.Fatalf("ExtractUint64: %v %v", v, err)
+	}
+	s, err := ExtractString(b, 2, 0)
+	if err != nil || s != "123" {
+		t.Fatalf("ExtractString: %v %v", s, err)
+	}
+	raw, err := Extract(b, 2)
+	if err != nil || BytesToHex(raw) != "dc0002da0003313233ce00000003" {
+		t.Fatalf("Extract: %x %v", raw, err)
+	}
+	if _, err = Extract(b, 3); err == nil {
+		t.Fatal("Extract: expected out of range error")
+	}
+	if _, err = Extract(b, 0, 0); err == nil {
+		t.Fatal("Extract: expected not array error")
+	}
+
+	// fixmap {"a": 1, "b": bin16 0102}
+	m, _ := HexToBytes("82a161cc01a162c500020102")
+	bs, err := ExtractBytes(m, 1)
+	if err != nil || BytesToHex(bs) != "0102" {
+		t.Fatalf("ExtractBytes: %x %v", bs, err)
+	}
+}
+
+func TestMarshalAbiUintOverflow(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Value uint64 `json:"value"`
+	}
+
+	fmt.Println("TestMarshalAbiUintOverflow...")
+
+	abi, err := ParseAbi([]byte(`{
+		"structs": [{"name": "item", "base": "", "fields": {"name": "string", "value": "uint8"}}],
+		"actions": [{"action_name": "item", "type": "item"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := MarshalAbi(Item{Name: "x", Value: 255}, abi, "", "item")
+	if err != nil || BytesToHex(b) != "dc0002da000178ccff" {
+		t.Fatalf("MarshalAbi: %x %v", b, err)
+	}
+	if _, err = MarshalAbi(Item{Name: "x", Value: 256}, abi, "", "item"); err == nil {
+		t.Fatal("MarshalAbi: expected overflow error")
+	}
+}
+
+func TestMarshalAbiFieldOrder(t *testing.T) {
+	// Go field order differs from the abi order transfer{from, to, value}
+	type Transfer struct {
+		Value uint64 `json:"value"`
+		To    string `json:"to"`
+		From  string `json:"from"`
+	}
+
+	fmt.Println("TestMarshalAbiFieldOrder...")
+
+	abi, err := ParseAbi([]byte(`{
+		"structs": [{"name": "transfer", "base": "", "fields": {"from": "string", "to": "string", "value": "uint64"}}],
+		"actions": [{"action_name": "transfer", "type": "transfer"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := MarshalAbi(Transfer{Value: 100, To: "bot", From: "bottos"}, abi, "bottos", "transfer")
+	if err != nil || BytesToHex(b) != "dc0003da0006626f74746f73da0003626f74cf0000000000000064" {
+		t.Fatalf("MarshalAbi: %x %v", b, err)
+	}
+
+	if _, err = MarshalAbi(struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}{"bottos", "bot"}, abi, "bottos", "transfer"); err == nil {
+		t.Fatal("MarshalAbi: expected missing field error")
+	}
+}
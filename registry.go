@@ -0,0 +1,197 @@
+package msgpack
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//ErrAbiNotFound is returned when no abi is known for a contract
+var ErrAbiNotFound = errors.New("abi not found")
+
+//DefaultAbiLoadTimeout bounds each load of an AbiRegistry from its source
+const DefaultAbiLoadTimeout = 30 * time.Second
+
+//AbiSource loads the abi of a contract, e.g. from a node or from disk
+type AbiSource interface {
+	GetAbi(ctx context.Context, contract string) (*ABI, error)
+}
+
+//AbiSourceFunc adapts an ordinary function to AbiSource
+type AbiSourceFunc func(ctx context.Context, contract string) (*ABI, error)
+
+//GetAbi calls f(ctx, contract)
+func (f AbiSourceFunc) GetAbi(ctx context.Context, contract string) (*ABI, error) {
+	return f(ctx, contract)
+}
+
+//AbiHash returns the hex encoded sha256 of a raw abi, for use as a version
+func AbiHash(abiRaw []byte) string {
+	sum := sha256.Sum256(abiRaw)
+	return hex.EncodeToString(sum[:])
+}
+
+type abiEntry struct {
+	latest   *ABI
+	versions map[string]*ABI
+}
+
+type abiCall struct {
+	done chan struct{}
+	abi  *ABI
+	err  error
+}
+
+//AbiRegistry stores parsed abis by contract name and optionally by version.
+//It is safe for concurrent use. Abis missing from the registry are loaded
+//from its source on first use; concurrent lookups of the same contract share
+//one load. Abis returned by the registry are shared and must not be modified.
+type AbiRegistry struct {
+	mu          sync.RWMutex
+	source      AbiSource
+	loadTimeout time.Duration
+	abis        map[string]*abiEntry
+	loading     map[string]*abiCall // running loads, dropped by Put and Invalidate
+}
+
+//NewAbiRegistry returns an empty registry that loads missing abis from
+//source. source may be nil, in which case only abis Put into the registry
+//are found.
+func NewAbiRegistry(source AbiSource) *AbiRegistry {
+	return &AbiRegistry{
+		source:      source,
+		loadTimeout: DefaultAbiLoadTimeout,
+		abis:        map[string]*abiEntry{},
+		loading:     map[string]*abiCall{},
+	}
+}
+
+//SetLoadTimeout bounds each load from the source, DefaultAbiLoadTimeout
+//unless changed. A timeout of 0 lets loads run until the source returns.
+func (r *AbiRegistry) SetLoadTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	r.loadTimeout = timeout
+	r.mu.Unlock()
+}
+
+//Get returns the latest abi of contract
+func (r *AbiRegistry) Get(contract string) (*ABI, error) {
+	return r.GetContext(context.Background(), contract)
+}
+
+//GetContext returns the latest abi of contract, loading it from the source
+//if it is not in the registry yet
+func (r *AbiRegistry) GetContext(ctx context.Context, contract string) (*ABI, error) {
+	r.mu.RLock()
+	if e, ok := r.abis[contract]; ok && e.latest != nil {
+		r.mu.RUnlock()
+		return e.latest, nil
+	}
+	r.mu.RUnlock()
+
+	if r.source == nil {
+		return nil, fmt.Errorf("%s: %w", contract, ErrAbiNotFound)
+	}
+
+	r.mu.Lock()
+	if e, ok := r.abis[contract]; ok && e.latest != nil {
+		r.mu.Unlock()
+		return e.latest, nil
+	}
+	call, ok := r.loading[contract]
+	if !ok {
+		call = &abiCall{done: make(chan struct{})}
+		r.loading[contract] = call
+		go r.load(contract, call, r.loadTimeout)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.abi, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+//load fetches the abi of contract for call. The result is only stored if
+//call is still the running load of contract, so a load that was overtaken by
+//Put or Invalidate does not bring back an older abi.
+func (r *AbiRegistry) load(contract string, call *abiCall, timeout time.Duration) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	call.abi, call.err = r.source.GetAbi(ctx, contract)
+	if call.err == nil && call.abi == nil {
+		call.err = fmt.Errorf("%s: %w", contract, ErrAbiNotFound)
+	}
+
+	r.mu.Lock()
+	if r.loading[contract] == call {
+		if call.err == nil {
+			r.entry(contract).latest = call.abi
+		}
+		delete(r.loading, contract)
+	}
+	r.mu.Unlock()
+
+	close(call.done)
+}
+
+//GetVersion returns the abi stored for contract under version by PutVersion
+func (r *AbiRegistry) GetVersion(contract string, version string) (*ABI, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if e, ok := r.abis[contract]; ok {
+		if abi, ok := e.versions[version]; ok {
+			return abi, nil
+		}
+	}
+	return nil, fmt.Errorf("%s@%s: %w", contract, version, ErrAbiNotFound)
+}
+
+//entry returns the entry of contract, creating it. r.mu must be held.
+func (r *AbiRegistry) entry(contract string) *abiEntry {
+	e, ok := r.abis[contract]
+	if !ok {
+		e = &abiEntry{versions: map[string]*ABI{}}
+		r.abis[contract] = e
+	}
+	return e
+}
+
+//Put stores abi as the latest abi of contract. A load of contract that is
+//still running does not replace it.
+func (r *AbiRegistry) Put(contract string, abi *ABI) {
+	r.mu.Lock()
+	r.entry(contract).latest = abi
+	delete(r.loading, contract)
+	r.mu.Unlock()
+}
+
+//PutVersion stores abi for contract under version, e.g. a block height or
+//AbiHash of the raw abi. The latest abi of contract is not changed.
+func (r *AbiRegistry) PutVersion(contract string, version string, abi *ABI) {
+	r.mu.Lock()
+	r.entry(contract).versions[version] = abi
+	r.mu.Unlock()
+}
+
+//Invalidate drops the latest abi and all versions of contract, the next Get
+//loads it again from the source. A load of contract that is still running is
+//not stored.
+func (r *AbiRegistry) Invalidate(contract string) {
+	r.mu.Lock()
+	delete(r.abis, contract)
+	delete(r.loading, contract)
+	r.mu.Unlock()
+}
@@ -0,0 +1,147 @@
+package msgpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAbiRegistry(t *testing.T) {
+	fmt.Println("TestAbiRegistry...")
+
+	var loads int32
+	source := AbiSourceFunc(func(ctx context.Context, contract string) (*ABI, error) {
+		atomic.AddInt32(&loads, 1)
+		if contract != "bottos" {
+			return nil, ErrAbiNotFound
+		}
+		time.Sleep(10 * time.Millisecond)
+		return ParseAbi([]byte(testAbi))
+	})
+	reg := NewAbiRegistry(source)
+
+	var wg sync.WaitGroup
+	abis := make([]*ABI, 8)
+	for i := range abis {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			abi, err := reg.Get("bottos")
+			if err != nil {
+				t.Error(err)
+			}
+			abis[i] = abi
+		}(i)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&loads) != 1 {
+		t.Fatalf("expected 1 load, got %d", loads)
+	}
+	for _, abi := range abis {
+		if abi != abis[0] {
+			t.Fatal("expected the same abi for all callers")
+		}
+	}
+
+	b, err := MarshalAbiEx(map[string]interface{}{"from": "bottos", "to": "bot", "value": uint64(1)}, abis[0], "bottos", "transfer")
+	if err != nil || len(b) == 0 {
+		t.Fatalf("MarshalAbiEx: %v", err)
+	}
+
+	if _, err = reg.Get("nosuchcontract"); !errors.Is(err, ErrAbiNotFound) {
+		t.Fatalf("expected ErrAbiNotFound, got %v", err)
+	}
+
+	reg.Invalidate("bottos")
+	if _, err = reg.Get("bottos"); err != nil || atomic.LoadInt32(&loads) != 3 {
+		t.Fatalf("expected reload after Invalidate: %v %d", err, loads)
+	}
+
+	old := &ABI{}
+	reg.PutVersion("bottos", AbiHash([]byte("old")), old)
+	if abi, err := reg.GetVersion("bottos", AbiHash([]byte("old"))); err != nil || abi != old {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if abi, _ := reg.Get("bottos"); abi == old {
+		t.Fatal("PutVersion must not change the latest abi")
+	}
+
+	reg.Put("bottos", old)
+	if abi, _ := reg.Get("bottos"); abi != old {
+		t.Fatal("Put must change the latest abi")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = NewAbiRegistry(source).GetContext(ctx, "bottos"); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestAbiRegistryStaleLoad(t *testing.T) {
+	fmt.Println("TestAbiRegistryStaleLoad...")
+
+	stale, fresh := &ABI{}, &ABI{}
+	started, release := make(chan struct{}), make(chan struct{})
+	var loads int32
+	source := AbiSourceFunc(func(ctx context.Context, contract string) (*ABI, error) {
+		if atomic.AddInt32(&loads, 1) > 1 {
+			return fresh, nil
+		}
+		close(started)
+		<-release
+		return stale, nil
+	})
+
+	// Invalidate while loading: the running load must not be stored
+	reg := NewAbiRegistry(source)
+	done := make(chan *ABI)
+	go func() {
+		abi, _ := reg.Get("bottos")
+		done <- abi
+	}()
+	<-started
+	reg.Invalidate("bottos")
+	close(release)
+	if abi := <-done; abi != stale {
+		t.Fatal("expected the running load to answer its callers")
+	}
+	if abi, err := reg.Get("bottos"); err != nil || abi != fresh {
+		t.Fatalf("expected a new load after Invalidate: %v", err)
+	}
+
+	// Put while loading: the running load must not replace the abi put
+	atomic.StoreInt32(&loads, 0)
+	started, release = make(chan struct{}), make(chan struct{})
+	reg = NewAbiRegistry(source)
+	go func() {
+		abi, _ := reg.Get("bottos")
+		done <- abi
+	}()
+	<-started
+	put := &ABI{}
+	reg.Put("bottos", put)
+	close(release)
+	<-done
+	if abi, _ := reg.Get("bottos"); abi != put {
+		t.Fatal("a running load replaced the abi put")
+	}
+}
+
+func TestAbiRegistryLoadTimeout(t *testing.T) {
+	fmt.Println("TestAbiRegistryLoadTimeout...")
+
+	reg := NewAbiRegistry(AbiSourceFunc(func(ctx context.Context, contract string) (*ABI, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	reg.SetLoadTimeout(10 * time.Millisecond)
+	if _, err := reg.Get("bottos"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
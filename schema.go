@@ -0,0 +1,325 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//JSONSchemaDraft is the JSON Schema dialect produced by ABI.JSONSchema
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var uintMax = map[string]uint64{
+	"uint8":  0xff,
+	"uint16": 0xffff,
+	"uint32": 0xffffffff,
+	"uint64": 0xffffffffffffffff,
+}
+
+//JSONSchema returns a JSON Schema (draft 2020-12) of the parameter object of
+//action, in the JSON form of values: uints are bounded integers, bytes are
+//hex strings, `T[]` is an array of at most 65535 items, `T?` is T or null and
+//a variant is an object {"type": <declared type>, "value": <value>}. Every
+//field is required and no other field is allowed, as in MarshalAbiEx.
+//Structs and variants are shared through $defs, so recursive types are
+//described too. MarshalAbiEx takes Go values rather than this JSON form,
+//ParamsFromJSON converts one to the other.
+func (abi *ABI) JSONSchema(action string) ([]byte, error) {
+	if err := abi.Validate(); err != nil {
+		return nil, err
+	}
+
+	typ := ""
+	for _, a := range abi.Actions {
+		if a.ActionName == action {
+			typ = a.Type
+			break
+		}
+	}
+	if typ == "" {
+		return nil, fmt.Errorf("JSONSchema: undefined action %s", action)
+	}
+
+	s := &schemaGen{abi: abi, defs: New()}
+	ref, err := s.schema(typ)
+	if err != nil {
+		return nil, fmt.Errorf("JSONSchema: action %s: %v", action, err)
+	}
+
+	root := New()
+	root.Set("$schema", JSONSchemaDraft)
+	root.Set("title", action)
+	for _, pair := range ref.GetStringPair() {
+		root.Set(pair.Key, pair.Value)
+	}
+	root.Set("$defs", s.defs)
+
+	b, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err = json.Indent(&out, b, "", "  "); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+type schemaGen struct {
+	abi  *ABI
+	defs *FeildMap // struct or variant name -> schema
+}
+
+func schemaOf(pairs ...interface{}) *FeildMap {
+	s := New()
+	for i := 0; i < len(pairs); i += 2 {
+		s.Set(pairs[i].(string), pairs[i+1])
+	}
+	return s
+}
+
+//defRef is the $ref to the $defs entry of name, escaped as a JSON pointer
+func defRef(name string) *FeildMap {
+	name = strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+	return schemaOf("$ref", "#/$defs/"+name)
+}
+
+func (s *schemaGen) schema(typ string) (*FeildMap, error) {
+	typ, err := resolveAbiType(s.abi, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		items, err := s.schema(strings.TrimSuffix(typ, "[]"))
+		if err != nil {
+			return nil, err
+		}
+		return schemaOf("type", "array", "items", items, "maxItems", 0xffff), nil
+	case strings.HasSuffix(typ, "?"):
+		elem, err := s.schema(strings.TrimSuffix(typ, "?"))
+		if err != nil {
+			return nil, err
+		}
+		return schemaOf("anyOf", []interface{}{elem, schemaOf("type", "null")}), nil
+	}
+
+	switch typ {
+	case "string":
+		return schemaOf("type", "string"), nil
+	case "uint8", "uint16", "uint32", "uint64":
+		return schemaOf("type", "integer", "minimum", 0, "maximum", uintMax[typ]), nil
+	case "bytes":
+		return schemaOf("type", "string", "contentEncoding", "base16", "pattern", "^([0-9a-fA-F]{2})*$", "maxLength", 2*0xffff), nil
+	}
+
+	if _, ok := s.defs.Get(typ); ok {
+		return defRef(typ), nil
+	}
+	// registered before the members, so recursive types refer to it
+	s.defs.Set(typ, nil)
+
+	var def *FeildMap
+	if variant := getAbiVariant(s.abi, typ); variant != nil {
+		var options []interface{}
+		for _, vt := range variant.Types {
+			value, err := s.schema(vt)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, schemaOf(
+				"type", "object",
+				"properties", schemaOf("type", schemaOf("const", vt), "value", value),
+				"required", []string{"type", "value"},
+				"additionalProperties", false,
+			))
+		}
+		def = schemaOf("description", "abi variant "+typ, "oneOf", options)
+	} else {
+		fields, err := abiStructFields(s.abi, typ)
+		if err != nil {
+			return nil, err
+		}
+		properties := New()
+		required := []string{}
+		for _, pair := range fields.GetStringPair() {
+			field, err := s.schema(pair.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", typ, pair.Key, err)
+			}
+			properties.Set(pair.Key, field)
+			required = append(required, pair.Key)
+		}
+		def = schemaOf(
+			"description", "abi struct "+typ,
+			"type", "object",
+			"properties", properties,
+			"required", required,
+			"additionalProperties", false,
+		)
+	}
+
+	s.defs.Set(typ, def)
+	return defRef(typ), nil
+}
+
+//ParamsFromJSON converts the JSON parameter object of action, in the form
+//described by JSONSchema, to the values MarshalAbiEx takes: integers become
+//the uint type of their field with full uint64 precision, hex strings
+//[]byte, arrays []interface{}, objects map[string]interface{} and variant
+//objects Variant. Values that do not fit their abi type are rejected with
+//their JSON path.
+func (abi *ABI) ParamsFromJSON(action string, data []byte) (map[string]interface{}, error) {
+	if err := abi.Validate(); err != nil {
+		return nil, err
+	}
+
+	typ := actionType(abi, action)
+	if typ == "" {
+		return nil, fmt.Errorf("ParamsFromJSON: undefined action %s", action)
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("ParamsFromJSON: %v", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("ParamsFromJSON: data after the parameter object")
+	}
+
+	params, err := abi.fromJSON("$", typ, v)
+	if err != nil {
+		return nil, fmt.Errorf("ParamsFromJSON: %v", err)
+	}
+	m, ok := params.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ParamsFromJSON: action %s is not a struct", action)
+	}
+	return m, nil
+}
+
+//fromJSON converts the decoded JSON value v at path to a value of typ
+func (abi *ABI) fromJSON(path string, typ string, v interface{}) (interface{}, error) {
+	typ, err := resolveAbiType(abi, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(typ, "?"):
+		if v == nil {
+			return nil, nil
+		}
+		return abi.fromJSON(path, strings.TrimSuffix(typ, "?"), v)
+	case strings.HasSuffix(typ, "[]"):
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not an array", path, jsonKind(v))
+		}
+		if len(items) > 0xffff {
+			return nil, fmt.Errorf("%s: %d items, at most %d allowed", path, len(items), 0xffff)
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			if out[i], err = abi.fromJSON(fmt.Sprintf("%s[%d]", path, i), strings.TrimSuffix(typ, "[]"), item); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	switch typ {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not a string", path, jsonKind(v))
+		}
+		return str, nil
+	case "uint8", "uint16", "uint32", "uint64":
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not an integer", path, jsonKind(v))
+		}
+		u, err := strconv.ParseUint(n.String(), 10, uintWidths[typ])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s is not a %s", path, n, typ)
+		}
+		return widenUint(u, typ), nil
+	case "bytes":
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not a hex string", path, jsonKind(v))
+		}
+		b, err := hex.DecodeString(str)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return b, nil
+	}
+
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: %s is not an object", path, jsonKind(v))
+	}
+
+	if variant := getAbiVariant(abi, typ); variant != nil {
+		vt, ok := obj["type"].(string)
+		if !ok || len(obj) != 2 {
+			return nil, fmt.Errorf("%s: variant %s is not {\"type\": ..., \"value\": ...}", path, typ)
+		}
+		for _, t := range variant.Types {
+			if t == vt {
+				value, err := abi.fromJSON(path+".value", vt, obj["value"])
+				if err != nil {
+					return nil, err
+				}
+				return Variant{Type: vt, Value: value}, nil
+			}
+		}
+		return nil, fmt.Errorf("%s.type: %s is not in variant %s %v", path, vt, typ, variant.Types)
+	}
+
+	fields, err := abiStructFields(abi, typ)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	for _, pair := range fields.GetStringPair() {
+		fv, ok := obj[pair.Key]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing field %s", path, pair.Key)
+		}
+		if out[pair.Key], err = abi.fromJSON(path+"."+pair.Key, pair.Value, fv); err != nil {
+			return nil, err
+		}
+	}
+	for k := range obj {
+		if _, ok := out[k]; !ok {
+			return nil, fmt.Errorf("%s: unknown field %s", path, k)
+		}
+	}
+	return out, nil
+}
+
+//jsonKind names the JSON kind of a decoded value for errors
+func jsonKind(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return strconv.Quote(v)
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
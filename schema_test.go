@@ -0,0 +1,141 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	fmt.Println("TestJSONSchema...")
+
+	abi, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "account_name", "type": "string"}],
+		"structs": [
+			{"name": "base", "base": "", "fields": {"from": "account_name"}},
+			{"name": "node", "base": "", "fields": {"id": "uint16", "next": "node?"}},
+			{"name": "a/b", "base": "", "fields": {"v": "uint8"}},
+			{"name": "transfer", "base": "base", "fields": {"value": "uint64", "data": "bytes", "nodes": "node[]", "odd": "a/b", "choice": "choice"}}
+		],
+		"variants": [{"name": "choice", "types": ["uint32", "string"]}],
+		"actions": [{"action_name": "transfer", "type": "transfer"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := abi.JSONSchema("transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err = dec.Decode(&schema); err != nil {
+		t.Fatalf("JSONSchema: %v\n%s", err, b)
+	}
+
+	js := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	defs := schema["$defs"].(map[string]interface{})
+	transfer := defs["transfer"].(map[string]interface{})
+	props := transfer["properties"].(map[string]interface{})
+
+	for _, c := range []struct{ got, expect string }{
+		{js(schema["$schema"]), `"https://json-schema.org/draft/2020-12/schema"`},
+		{js(schema["$ref"]), `"#/$defs/transfer"`},
+		{js(transfer["required"]), `["from","value","data","nodes","odd","choice"]`},
+		{js(transfer["additionalProperties"]), `false`},
+		{js(props["from"]), `{"type":"string"}`},
+		{js(props["value"]), `{"maximum":18446744073709551615,"minimum":0,"type":"integer"}`},
+		{js(props["data"]), `{"contentEncoding":"base16","maxLength":131070,"pattern":"^([0-9a-fA-F]{2})*$","type":"string"}`},
+		{js(props["nodes"]), `{"items":{"$ref":"#/$defs/node"},"maxItems":65535,"type":"array"}`},
+		{js(props["odd"]), `{"$ref":"#/$defs/a~1b"}`},
+		{js(defs["node"].(map[string]interface{})["properties"]), `{"id":{"maximum":65535,"minimum":0,"type":"integer"},"next":{"anyOf":[{"$ref":"#/$defs/node"},{"type":"null"}]}}`},
+		{js(defs["choice"].(map[string]interface{})["oneOf"].([]interface{})[0]), `{"additionalProperties":false,"properties":{"type":{"const":"uint32"},"value":{"maximum":4294967295,"minimum":0,"type":"integer"}},"required":["type","value"],"type":"object"}`},
+	} {
+		if c.got != c.expect {
+			t.Fatalf("JSONSchema: got %s, want %s", c.got, c.expect)
+		}
+	}
+
+	if _, err = abi.JSONSchema("nosuchaction"); err == nil {
+		t.Fatal("JSONSchema: expected undefined action error")
+	}
+}
+
+func TestParamsFromJSON(t *testing.T) {
+	fmt.Println("TestParamsFromJSON...")
+
+	abi, err := ParseAbi([]byte(`{
+		"structs": [
+			{"name": "node", "base": "", "fields": {"id": "uint16", "next": "node?"}},
+			{"name": "transfer", "base": "", "fields": {"from": "string", "value": "uint64", "data": "bytes", "nodes": "node[]", "choice": "choice"}}
+		],
+		"variants": [{"name": "choice", "types": ["uint32", "string"]}],
+		"actions": [{"action_name": "transfer", "type": "transfer"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := abi.ParamsFromJSON("transfer", []byte(`{
+		"from": "bottos",
+		"value": 18446744073709551615,
+		"data": "00ff",
+		"nodes": [{"id": 1, "next": {"id": 2, "next": null}}],
+		"choice": {"type": "uint32", "value": 7}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalAbiEx(params, abi, "", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, err := MarshalAbiEx(map[string]interface{}{
+		"from":  "bottos",
+		"value": uint64(18446744073709551615),
+		"data":  []byte{0, 0xff},
+		"nodes": []interface{}{
+			map[string]interface{}{"id": uint16(1), "next": map[string]interface{}{"id": uint16(2), "next": nil}},
+		},
+		"choice": Variant{Type: "uint32", Value: uint32(7)},
+	}, abi, "", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, expect) {
+		t.Fatalf("ParamsFromJSON: %x, want %x", b, expect)
+	}
+
+	for in, expect := range map[string]string{
+		`{"from": "a", "value": 1, "data": "", "nodes": [{"id": 65536, "next": null}], "choice": {"type": "string", "value": ""}}`: "$.nodes[0].id: 65536 is not a uint16",
+		`{"from": "a", "value": -1, "data": "", "nodes": [], "choice": {"type": "string", "value": ""}}`:                           "$.value: -1 is not a uint64",
+		`{"from": "a", "value": 1, "data": "0g", "nodes": [], "choice": {"type": "string", "value": ""}}`:                          "$.data: encoding/hex: invalid byte",
+		`{"from": "a", "value": 1, "data": "", "nodes": [], "choice": {"type": "bytes", "value": ""}}`:                             "$.choice.type: bytes is not in variant choice",
+		`{"from": "a", "value": 1, "data": "", "nodes": [], "choice": {"type": "string", "value": ""}, "memo": ""}`:                "$: unknown field memo",
+		`{"from": "a", "value": 1, "data": "", "choice": {"type": "string", "value": ""}}`:                                         "$: missing field nodes",
+	} {
+		_, err := abi.ParamsFromJSON("transfer", []byte(in))
+		if err == nil || !strings.Contains(err.Error(), expect) {
+			t.Errorf("ParamsFromJSON(%s): got %v, want %q", in, err, expect)
+		}
+	}
+
+	// an action type that is not a struct is rejected by Validate
+	scalar, err := ParseAbi([]byte(`{
+		"types": [{"new_type_name": "amt", "type": "uint64"}],
+		"actions": [{"action_name": "a", "type": "amt"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = scalar.ParamsFromJSON("a", []byte("5")); err == nil {
+		t.Fatal("ParamsFromJSON: expected error for a non-struct action type")
+	}
+}
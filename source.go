@@ -0,0 +1,215 @@
+package msgpack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+)
+
+//DefaultAbiEndpoint is the rpc endpoint of a local node
+const DefaultAbiEndpoint = "http://127.0.0.1:8080/rpc"
+
+//AbiFetchError is returned by HTTPAbiSource when an abi cannot be fetched.
+//StatusCode is 0 if no http response was received.
+type AbiFetchError struct {
+	Contract   string
+	Endpoint   string
+	StatusCode int
+	Err        error
+}
+
+func (e *AbiFetchError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("fetch abi of %s from %s: http status %d: %v", e.Contract, e.Endpoint, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("fetch abi of %s from %s: %v", e.Contract, e.Endpoint, e.Err)
+}
+
+//Unwrap returns the underlying error
+func (e *AbiFetchError) Unwrap() error {
+	return e.Err
+}
+
+//HTTPAbiSource fetches abis from a node with the Chain.GetAbi rpc
+type HTTPAbiSource struct {
+	//Endpoint is the rpc url of the node, e.g. DefaultAbiEndpoint
+	Endpoint string
+	//Client is used for requests, http.DefaultClient if nil
+	Client *http.Client
+	//Timeout bounds each attempt, no timeout if 0
+	Timeout time.Duration
+	//Retries is the number of extra attempts after network errors and 5xx responses
+	Retries int
+	//RetryDelay is the wait between attempts
+	RetryDelay time.Duration
+}
+
+//NewHTTPAbiSource returns a source for endpoint with a 10s timeout and 2 retries
+func NewHTTPAbiSource(endpoint string) *HTTPAbiSource {
+	return &HTTPAbiSource{
+		Endpoint:   endpoint,
+		Timeout:    10 * time.Second,
+		Retries:    2,
+		RetryDelay: 500 * time.Millisecond,
+	}
+}
+
+//GetAbi fetches and parses the abi of contract
+func (s *HTTPAbiSource) GetAbi(ctx context.Context, contract string) (*ABI, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var abi *ABI
+		var retry bool
+		abi, retry, err = s.fetch(ctx, contract)
+		if err == nil {
+			return abi, nil
+		}
+		if !retry || attempt >= s.Retries {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(s.RetryDelay):
+		case <-ctx.Done():
+			return nil, &AbiFetchError{Contract: contract, Endpoint: s.Endpoint, Err: ctx.Err()}
+		}
+	}
+}
+
+//fetch makes one attempt, retry reports whether the error is transient
+func (s *HTTPAbiSource) fetch(ctx context.Context, contract string) (abi *ABI, retry bool, err error) {
+	fail := func(status int, err error) *AbiFetchError {
+		return &AbiFetchError{Contract: contract, Endpoint: s.Endpoint, StatusCode: status, Err: err}
+	}
+
+	request := &bytes.Buffer{}
+	enc := json.NewEncoder(request)
+	enc.SetEscapeHTML(false)
+	if err = enc.Encode(map[string]string{"contract": contract}); err != nil {
+		return nil, false, fail(0, err)
+	}
+	form := url.Values{}
+	form.Set("service", "bottos")
+	form.Set("method", "Chain.GetAbi")
+	form.Set("request", strings.TrimSpace(request.String()))
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, false, fail(0, err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, fail(0, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fail(resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode >= 500, fail(resp.StatusCode, fmt.Errorf("%s", strings.TrimSpace(string(body))))
+	}
+
+	jss, err := simplejson.NewJson(body)
+	if err != nil {
+		return nil, false, fail(resp.StatusCode, fmt.Errorf("invalid response: %v", err))
+	}
+
+	result := jss.Get("result")
+	abistring, err := result.String()
+	if err != nil && result.Interface() != nil {
+		// some nodes return the abi as a json object rather than a string
+		raw, _ := result.MarshalJSON()
+		abistring = string(raw)
+	}
+	if len(abistring) <= 0 {
+		return nil, false, fail(resp.StatusCode, ErrAbiNotFound)
+	}
+
+	abi, err = ParseAbi([]byte(abistring))
+	if err != nil {
+		return nil, false, fail(resp.StatusCode, fmt.Errorf("parse abi: %v", err))
+	}
+	return abi, false, nil
+}
+
+//AbiFileExt is the file extension of abi files
+const AbiFileExt = ".abi"
+
+//LoadAbiFile reads and parses an abi file
+func LoadAbiFile(path string) (*ABI, error) {
+	abiRaw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	abi, err := ParseAbi(abiRaw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return abi, nil
+}
+
+//LoadAbiDir reads and parses every <contract>.abi file in dir, keyed by contract name
+func LoadAbiDir(dir string) (map[string]*ABI, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	abis := map[string]*ABI{}
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != AbiFileExt {
+			continue
+		}
+
+		abi, err := LoadAbiFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		abis[strings.TrimSuffix(f.Name(), AbiFileExt)] = abi
+	}
+	return abis, nil
+}
+
+//DirAbiSource loads abis from <Dir>/<contract>.abi
+type DirAbiSource struct {
+	Dir string
+}
+
+//GetAbi reads and parses the abi file of contract
+func (s DirAbiSource) GetAbi(ctx context.Context, contract string) (*ABI, error) {
+	if contract == "" || strings.ContainsAny(contract, `/\`) || contract == "." || contract == ".." {
+		return nil, fmt.Errorf("invalid contract name %q", contract)
+	}
+
+	abi, err := LoadAbiFile(filepath.Join(s.Dir, contract+AbiFileExt))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", contract, ErrAbiNotFound)
+	}
+	return abi, err
+}
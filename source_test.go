@@ -0,0 +1,104 @@
+package msgpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPAbiSource(t *testing.T) {
+	fmt.Println("TestHTTPAbiSource...")
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.FormValue("service") != "bottos" || r.FormValue("method") != "Chain.GetAbi" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		switch r.FormValue("request") {
+		case `{"contract":"a\"&b=c"}`:
+			fmt.Fprintf(w, `{"errcode":0,"result":%s}`, strconv.Quote(testAbi))
+		case `{"contract":"flaky"}`:
+			if n == 1 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintf(w, `{"errcode":0,"result":%s}`, testAbi)
+		case `{"contract":"slow"}`:
+			time.Sleep(200 * time.Millisecond)
+		default:
+			fmt.Fprint(w, `{"errcode":0,"result":""}`)
+		}
+	}))
+	defer srv.Close()
+
+	src := NewHTTPAbiSource(srv.URL)
+	src.RetryDelay = time.Millisecond
+
+	abi, err := src.GetAbi(context.Background(), `a"&b=c`)
+	if err != nil || len(abi.Actions) != 2 {
+		t.Fatalf("GetAbi escaped: %v", err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	abi, err = src.GetAbi(context.Background(), "flaky")
+	if err != nil || len(abi.Actions) != 2 || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("GetAbi retry: %v %d", err, calls)
+	}
+
+	_, err = src.GetAbi(context.Background(), "nosuchcontract")
+	var fetchErr *AbiFetchError
+	if !errors.Is(err, ErrAbiNotFound) || !errors.As(err, &fetchErr) || fetchErr.Contract != "nosuchcontract" {
+		t.Fatalf("GetAbi not found: %v", err)
+	}
+
+	src.Timeout = 20 * time.Millisecond
+	src.Retries = 0
+	_, err = src.GetAbi(context.Background(), "slow")
+	if !errors.As(err, &fetchErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetAbi timeout: %v", err)
+	}
+}
+
+func TestDirAbiSource(t *testing.T) {
+	fmt.Println("TestDirAbiSource...")
+
+	dir, err := ioutil.TempDir("", "abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "bottos.abi"), []byte(testAbi), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := NewAbiRegistry(DirAbiSource{Dir: dir})
+	abi, err := reg.Get("bottos")
+	if err != nil || len(abi.Structs) != 3 {
+		t.Fatalf("DirAbiSource: %v", err)
+	}
+
+	if _, err = reg.Get("nosuchcontract"); !errors.Is(err, ErrAbiNotFound) {
+		t.Fatalf("expected ErrAbiNotFound, got %v", err)
+	}
+	if _, err = reg.Get("../bottos"); err == nil {
+		t.Fatal("expected invalid contract name error")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not an abi"), 0644)
+	abis, err := LoadAbiDir(dir)
+	if err != nil || len(abis) != 1 || abis["bottos"] == nil {
+		t.Fatalf("LoadAbiDir: %v %v", abis, err)
+	}
+}
@@ -0,0 +1,60 @@
+// Package systemabi embeds the abis of the Bottos system contracts, so that
+// offline tools and tests can encode system actions without a running node.
+//
+// Contracts and actions:
+//
+//	bottos       newaccount, transfer
+//	usermng      userreg
+//	assetmng     assetreg
+//	datafilemng  datafilereg
+//	datareqmng   datareqreg
+//	datadealmng  presalereq, buyassetreq
+//	goodsmng     goodsproreq
+package systemabi
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+//go:embed *.abi
+var files embed.FS
+
+//Contracts lists the names of the embedded system contracts
+func Contracts() []string {
+	entries, _ := files.ReadDir(".")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), msgpack.AbiFileExt))
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Raw returns the abi json of a system contract
+func Raw(contract string) ([]byte, error) {
+	abiRaw, err := files.ReadFile(contract + msgpack.AbiFileExt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", contract, msgpack.ErrAbiNotFound)
+	}
+	return abiRaw, nil
+}
+
+//Get returns a freshly parsed abi of a system contract
+func Get(contract string) (*msgpack.ABI, error) {
+	abiRaw, err := Raw(contract)
+	if err != nil {
+		return nil, err
+	}
+	return msgpack.ParseAbiStrict(abiRaw)
+}
+
+//Source serves the embedded system contract abis as a msgpack.AbiSource
+var Source msgpack.AbiSource = msgpack.AbiSourceFunc(func(ctx context.Context, contract string) (*msgpack.ABI, error) {
+	return Get(contract)
+})
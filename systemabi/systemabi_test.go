@@ -0,0 +1,65 @@
+package systemabi
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/bottos-project/msgpack-go"
+)
+
+func TestContracts(t *testing.T) {
+	contracts := Contracts()
+	if len(contracts) != 7 {
+		t.Fatalf("Contracts: %v", contracts)
+	}
+
+	for _, c := range contracts {
+		if _, err := Get(c); err != nil {
+			t.Errorf("%s: %v", c, err)
+		}
+	}
+
+	if _, err := Get("nosuchcontract"); err == nil {
+		t.Fatal("expected error for unknown contract")
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	type Transfer struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Value uint64 `json:"value"`
+	}
+
+	reg := msgpack.NewAbiRegistry(Source)
+	abi, err := reg.Get("bottos")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := msgpack.MarshalAbi(Transfer{From: "bottos", To: "bot", Value: 100000000000}, abi, "bottos", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(b) != "dc0003da0006626f74746f73da0003626f74cf000000174876e800" {
+		t.Fatalf("transfer: %x", b)
+	}
+}
+
+func TestAssetReg(t *testing.T) {
+	abi, err := Get("assetmng")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// captured from an assetreg transaction, see TestAssetfileReg
+	b, _ := hex.DecodeString("dc0002da00206230356563613430363362363131653861313164623763303833663930643061dc000ada0003626f74da00046e616d65cf000000000000000eda0005312d312d31da0000da004066636336386466646632316639343432616134306361363062313262396639653332383239663332346566343532653730656533623434313465363164396434ce5b195680ce00000001cf00038d7e9ed09f00da0003313233")
+	fm, err := msgpack.UnmarshalAbiEx(b, abi, "assetmng", "assetreg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := fm.Get("info")
+	if price, _ := info.(*msgpack.FeildMap).Get("price"); price != uint64(999999900000000) {
+		t.Fatalf("assetreg: %v", price)
+	}
+}
@@ -0,0 +1,123 @@
+package msgpack
+
+import (
+	"bytes"
+	"fmt"
+)
+
+//getAbiTable returns the abi table named name
+func getAbiTable(abi *ABI, name string) (*ABITable, error) {
+	if abi == nil {
+		return nil, fmt.Errorf("abi is nil")
+	}
+	for i := range abi.Tables {
+		if abi.Tables[i].Name == name {
+			return &abi.Tables[i], nil
+		}
+	}
+	return nil, fmt.Errorf("undefined table %s", name)
+}
+
+//EncodeTableRow is to serialize a table row, the row is encoded as the
+//table's abi struct type
+func EncodeTableRow(abi *ABI, table string, row map[string]interface{}) ([]byte, error) {
+	tbl, err := getAbiTable(abi, table)
+	if err != nil {
+		return []byte{}, fmt.Errorf("EncodeTableRow: %v", err)
+	}
+
+	writer := &bytes.Buffer{}
+	err = encodeAbiEx("", "", writer, row, abi, tbl.Type)
+	if err != nil {
+		return []byte{}, err
+	}
+	return writer.Bytes(), nil
+}
+
+//DecodeTableRow is to unserialize a table row into an ordered FeildMap
+func DecodeTableRow(abi *ABI, table string, data []byte) (*FeildMap, error) {
+	tbl, err := getAbiTable(abi, table)
+	if err != nil {
+		return nil, fmt.Errorf("DecodeTableRow: %v", err)
+	}
+
+	r := bytes.NewReader(data)
+	row, err := decodeAbiEx("", "", r, abi, tbl.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("DecodeTableRow: %d trailing bytes", r.Len())
+	}
+	return row, nil
+}
+
+//EncodeTableKey is to serialize a table key. Keys are given in the order of
+//the table's key_names and encoded back to back as their key_types.
+func EncodeTableKey(abi *ABI, table string, keys ...interface{}) ([]byte, error) {
+	tbl, err := getAbiTable(abi, table)
+	if err != nil {
+		return []byte{}, fmt.Errorf("EncodeTableKey: %v", err)
+	}
+
+	if len(keys) != len(tbl.KeyTypes) {
+		return []byte{}, fmt.Errorf("EncodeTableKey: table %s has %d keys, got %d", table, len(tbl.KeyTypes), len(keys))
+	}
+
+	writer := &bytes.Buffer{}
+	for i, key := range keys {
+		err = encodeAbiValueEx("", "", writer, abi, tbl.KeyTypes[i], key)
+		if err != nil {
+			return []byte{}, fmt.Errorf("EncodeTableKey: key %d: %v", i, err)
+		}
+	}
+	return writer.Bytes(), nil
+}
+
+//EncodeTableRowKey is to serialize the key of a table row, taking the key
+//values from the row fields named by key_names
+func EncodeTableRowKey(abi *ABI, table string, row map[string]interface{}) ([]byte, error) {
+	tbl, err := getAbiTable(abi, table)
+	if err != nil {
+		return []byte{}, fmt.Errorf("EncodeTableRowKey: %v", err)
+	}
+
+	keys := make([]interface{}, len(tbl.KeyNames))
+	for i, name := range tbl.KeyNames {
+		key, ok := row[name]
+		if !ok {
+			return []byte{}, fmt.Errorf("EncodeTableRowKey: key %s not found in row", name)
+		}
+		keys[i] = key
+	}
+	return EncodeTableKey(abi, table, keys...)
+}
+
+//DecodeTableKey is to unserialize a table key into an ordered FeildMap of
+//key_names to values
+func DecodeTableKey(abi *ABI, table string, data []byte) (*FeildMap, error) {
+	tbl, err := getAbiTable(abi, table)
+	if err != nil {
+		return nil, fmt.Errorf("DecodeTableKey: %v", err)
+	}
+
+	if len(tbl.KeyNames) != len(tbl.KeyTypes) {
+		return nil, fmt.Errorf("DecodeTableKey: table %s key_names and key_types mismatch", table)
+	}
+
+	r := bytes.NewReader(data)
+	keys := New()
+	for i, name := range tbl.KeyNames {
+		val, err := decodeAbiValueEx("", "", r, abi, tbl.KeyTypes[i])
+		if err != nil {
+			return nil, fmt.Errorf("DecodeTableKey: %s: %v", name, err)
+		}
+		keys.Set(name, val)
+	}
+
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("DecodeTableKey: %d trailing bytes", r.Len())
+	}
+	return keys, nil
+}
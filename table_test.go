@@ -0,0 +1,69 @@
+package msgpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestTableRow(t *testing.T) {
+	fmt.Println("TestTableRow...")
+
+	abi, err := ParseAbiStrict([]byte(`{
+		"structs": [
+			{"name": "assetinfo", "base": "", "fields": {"user_name": "string", "asset_name": "string", "asset_type": "uint64", "price": "uint64"}}
+		],
+		"tables": [
+			{"table_name": "assetreginfo", "index_type": "string", "key_names": ["user_name", "asset_type"], "key_types": ["string", "uint64"], "type": "assetinfo"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := map[string]interface{}{
+		"user_name":  "btd121",
+		"asset_name": "assetnametest",
+		"asset_type": uint64(12),
+		"price":      uint64(999),
+	}
+	b, err := EncodeTableRow(abi, "assetreginfo", row)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fm, err := DecodeTableRow(abi, "assetreginfo", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, _ := json.Marshal(fm)
+	if string(js) != `{"user_name":"btd121","asset_name":"assetnametest","asset_type":12,"price":999}` {
+		t.Fatalf("DecodeTableRow: %s", js)
+	}
+
+	key, err := EncodeTableRowKey(abi, "assetreginfo", row)
+	if err != nil || BytesToHex(key) != "da0006627464313231cf000000000000000c" {
+		t.Fatalf("EncodeTableRowKey: %x %v", key, err)
+	}
+
+	keys, err := DecodeTableKey(abi, "assetreginfo", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := keys.Get("asset_type"); v != uint64(12) {
+		t.Fatalf("DecodeTableKey: %v", keys)
+	}
+
+	if _, err = EncodeTableKey(abi, "assetreginfo", "btd121"); err == nil {
+		t.Fatal("EncodeTableKey: expected key count error")
+	}
+	if _, err = DecodeTableRow(abi, "nosuchtable", b); err == nil {
+		t.Fatal("DecodeTableRow: expected undefined table error")
+	}
+	if _, err = DecodeTableRow(abi, "assetreginfo", append(b, b...)); err == nil {
+		t.Fatal("DecodeTableRow: expected trailing bytes error")
+	}
+	if _, err = DecodeTableRow(abi, "assetreginfo", b[:len(b)-1]); err == nil {
+		t.Fatal("DecodeTableRow: expected truncated row error")
+	}
+}
@@ -0,0 +1,90 @@
+package msgpack
+
+//Type is the family of a msgpack value as reported by PeekType
+type Type int
+
+const (
+	//InvalidType is returned for reserved type identifiers
+	InvalidType Type = iota
+	//NilType is nil
+	NilType
+	//BoolType is true or false
+	BoolType
+	//UintType is positive fixnum and uint8 to uint64
+	UintType
+	//IntType is negative fixnum and int8 to int64
+	IntType
+	//FloatType is float32 and float64
+	FloatType
+	//StrType is fixraw and str8 to str32
+	StrType
+	//BinType is bin8 to bin32
+	BinType
+	//ArrayType is fixarray, array16 and array32
+	ArrayType
+	//MapType is fixmap, map16 and map32
+	MapType
+	//ExtType is fixext and ext8 to ext32
+	ExtType
+)
+
+var typeNames = [...]string{
+	InvalidType: "invalid",
+	NilType:     "nil",
+	BoolType:    "bool",
+	UintType:    "uint",
+	IntType:     "int",
+	FloatType:   "float",
+	StrType:     "str",
+	BinType:     "bin",
+	ArrayType:   "array",
+	MapType:     "map",
+	ExtType:     "ext",
+}
+
+func (t Type) String() string {
+	if t < 0 || int(t) >= len(typeNames) {
+		return typeNames[InvalidType]
+	}
+	return typeNames[t]
+}
+
+//typeOf returns the family of a value from its first byte
+func typeOf(c byte) Type {
+	switch {
+	case c <= POSFIXNUMMAX:
+		return UintType
+	case c <= FIXMAPMAX:
+		return MapType
+	case c <= FIXARRAYMAX:
+		return ArrayType
+	case c <= FIXRAWMAX:
+		return StrType
+	case c >= NEGFIXNUM:
+		return IntType
+	}
+
+	switch c {
+	case NIL:
+		return NilType
+	case FALSE, TRUE:
+		return BoolType
+	case BIN8, BIN16, BIN32:
+		return BinType
+	case EXT8, EXT16, EXT32, FIXEXT1, FIXEXT2, FIXEXT4, FIXEXT8, FIXEXT16:
+		return ExtType
+	case FLOAT32, FLOAT64:
+		return FloatType
+	case UINT8, UINT16, UINT32, UINT64:
+		return UintType
+	case INT8, INT16, INT32, INT64:
+		return IntType
+	case STR8, STR16, STR32:
+		return StrType
+	case ARRAY16, ARRAY32:
+		return ArrayType
+	case MAP16, MAP32:
+		return MapType
+	}
+	return InvalidType
+}
@@ -26,8 +26,12 @@
 package msgpack
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
 )
 
 type (
@@ -52,33 +56,92 @@ const (
 	FIXRAWMAX     = 0xbf
 	//FIRSTBYTEMASK is first byte mask
 	FIRSTBYTEMASK = 0xf
+
+	//POSFIXNUMMAX is positive fixnum maxnum
+	POSFIXNUMMAX = 0x7f
+	//FIXRAWMASK is fix raw length mask
+	FIXRAWMASK = 0x1f
+	//NIL is nil type identifier
+	NIL = 0xc0
+	//FALSE is false type identifier
+	FALSE = 0xc2
+	//TRUE is true type identifier
+	TRUE = 0xc3
+	//BIN8 is byte array type identifier
+	BIN8 = 0xc4
+	//BIN32 is byte array type identifier
+	BIN32 = 0xc6
+	//EXT8 is ext type identifier
+	EXT8 = 0xc7
+	//EXT16 is ext type identifier
+	EXT16 = 0xc8
+	//EXT32 is ext type identifier
+	EXT32 = 0xc9
+	//FLOAT32 is float32
+	FLOAT32 = 0xca
+	//FLOAT64 is float64
+	FLOAT64 = 0xcb
+	//INT8 is int8
+	INT8 = 0xd0
+	//INT16 is int16
+	INT16 = 0xd1
+	//INT32 is int32
+	INT32 = 0xd2
+	//INT64 is int64
+	INT64 = 0xd3
+	//FIXEXT1 is fixext type identifier
+	FIXEXT1 = 0xd4
+	//FIXEXT2 is fixext type identifier
+	FIXEXT2 = 0xd5
+	//FIXEXT4 is fixext type identifier
+	FIXEXT4 = 0xd6
+	//FIXEXT8 is fixext type identifier
+	FIXEXT8 = 0xd7
+	//FIXEXT16 is fixext type identifier
+	FIXEXT16 = 0xd8
+	//STR8 is string type identifier
+	STR8 = 0xd9
+	//STR32 is string type identifier
+	STR32 = 0xdb
+	//ARRAY32 is array size type identifier
+	ARRAY32 = 0xdd
+	//MAP16 is map size type identifier
+	MAP16 = 0xde
+	//MAP32 is map size type identifier
+	MAP32 = 0xdf
 )
 
 func readByte(reader io.Reader) (v uint8, err error) {
 	var data Bytes1
-	_, e := reader.Read(data[0:])
+	_, e := io.ReadFull(reader, data[0:])
 	if e != nil {
 		return 0, e
 	}
 	return data[0], nil
 }
 
-//UnpackUint8 is to unpack message
-func UnpackUint8(reader io.Reader) (v uint8, err error) {
+func readHeader(reader io.Reader, header uint8, name string) error {
 	c, e := readByte(reader)
-	if e == nil && c == UINT8  {
-		v, err = readByte(reader)
-		if err == nil {
-			return v, nil
-		}
+	if e != nil {
+		return e
 	}
+	if c != header {
+		return fmt.Errorf("Not %s: 0x%02x", name, c)
+	}
+	return nil
+}
 
-	return 0, err
+//UnpackUint8 is to unpack message
+func UnpackUint8(reader io.Reader) (v uint8, err error) {
+	if err = readHeader(reader, UINT8, "Uint8"); err != nil {
+		return 0, err
+	}
+	return readByte(reader)
 }
 
 func readUint16(reader io.Reader) (v uint16, n int, err error) {
 	var data Bytes2
-	n, e := reader.Read(data[0:])
+	n, e := io.ReadFull(reader, data[0:])
 	if e != nil {
 		return 0, n, e
 	}
@@ -87,20 +150,16 @@ func readUint16(reader io.Reader) (v uint16, n int, err error) {
 
 //UnpackUint16 is to unpack message
 func UnpackUint16(reader io.Reader) (v uint16, err error) {
-	c, e := readByte(reader)
-	if e == nil && c == UINT16  {
-		v, _, err = readUint16(reader)
-		if err == nil {
-			return v, nil
-		}
+	if err = readHeader(reader, UINT16, "Uint16"); err != nil {
+		return 0, err
 	}
-
-	return 0, err
+	v, _, err = readUint16(reader)
+	return v, err
 }
 
 func readUint32(reader io.Reader) (v uint32, n int, err error) {
 	var data Bytes4
-	n, e := reader.Read(data[0:])
+	n, e := io.ReadFull(reader, data[0:])
 	if e != nil {
 		return 0, n, e
 	}
@@ -109,20 +168,16 @@ func readUint32(reader io.Reader) (v uint32, n int, err error) {
 
 //UnpackUint32 is to unpack message
 func UnpackUint32(reader io.Reader) (v uint32, err error) {
-	c, e := readByte(reader)
-	if e == nil && c == UINT32  {
-		v, _, err = readUint32(reader)
-		if err == nil {
-			return v, nil
-		}
+	if err = readHeader(reader, UINT32, "Uint32"); err != nil {
+		return 0, err
 	}
-
-	return 0, err
+	v, _, err = readUint32(reader)
+	return v, err
 }
 
 func readUint64(reader io.Reader) (v uint64, n int, err error) {
 	var data Bytes8
-	n, e := reader.Read(data[0:])
+	n, e := io.ReadFull(reader, data[0:])
 	if e != nil {
 		return 0, n, e
 	}
@@ -131,68 +186,515 @@ func readUint64(reader io.Reader) (v uint64, n int, err error) {
 
 //UnpackUint64 is to unpack message
 func UnpackUint64(reader io.Reader) (v uint64, err error) {
-	c, e := readByte(reader)
-	if e == nil && c == UINT64  {
-		v, _, err = readUint64(reader)
-		if err == nil {
-			return v, nil
-		}
+	if err = readHeader(reader, UINT64, "Uint64"); err != nil {
+		return 0, err
 	}
-
-	return 0, err
+	v, _, err = readUint64(reader)
+	return v, err
 }
 
 //UnpackArraySize is to unpack message
 func UnpackArraySize(reader io.Reader) (size uint16, err error) {
-	c, e := readByte(reader)
+	if err = readHeader(reader, ARRAY16, "Array 16"); err != nil {
+		return 0, err
+	}
+	size, _, err = readUint16(reader)
+	return size, err
+}
+
+//UnpackStr16 is to unpack message
+func UnpackStr16(reader io.Reader) (string, error) {
+	if e := readHeader(reader, STR16, "Str 16"); e != nil {
+		return "", e
+	}
+	size, _, e := readUint16(reader)
 	if e != nil {
-		return 0, e
+		return "", e
+	}
+	value := make([]byte, size)
+	if _, e = io.ReadFull(reader, value); e != nil {
+		return "", e
 	}
+	return string(value), nil
+}
 
-	header := uint16(c)
-	if header != ARRAY16 {
-		return 0, fmt.Errorf("Not Array 16")
+//UnpackBin16 is to unpack message
+func UnpackBin16(reader io.Reader) ([]byte, error) {
+	if e := readHeader(reader, BIN16, "Bin 16"); e != nil {
+		return []byte{}, e
+	}
+	size, _, e := readUint16(reader)
+	if e != nil {
+		return []byte{}, e
 	}
+	value := make([]byte, size)
+	if _, e = io.ReadFull(reader, value); e != nil {
+		return []byte{}, e
+	}
+	return value, nil
+}
 
-	size, _, e = readUint16(reader)
+func readLength(reader io.Reader, size int) (uint64, error) {
+	var data Bytes8
+	_, e := io.ReadFull(reader, data[:size])
 	if e != nil {
 		return 0, e
 	}
 
-	return size, nil
+	var length uint64
+	for i := 0; i < size; i++ {
+		length = length<<8 | uint64(data[i])
+	}
+	return length, nil
 }
 
-//UnpackStr16 is to unpack message
-func UnpackStr16(reader io.Reader) (string, error) {
-	c, e := readByte(reader)
-	if e == nil && c == STR16  {
-		size, _, e := readUint16(reader)
-		if e == nil {
-			value := make([]byte, size)
-			n, e := reader.Read(value)
-			if e == nil && uint16(n) == size {
-				return string(value), nil
+//skipValue consumes exactly one complete value from reader, including all
+//elements of nested arrays and maps. Every byte consumed is copied to w if
+//w is not nil. Any msgpack type is accepted, not only the subset we encode.
+func skipValue(reader io.Reader, w io.Writer) error {
+	if w != nil {
+		reader = io.TeeReader(reader, w)
+	}
+
+	pending := uint64(1)
+	for pending > 0 {
+		pending--
+
+		var data Bytes1
+		if _, e := io.ReadFull(reader, data[:]); e != nil {
+			return e
+		}
+		c := data[0]
+
+		var skip uint64
+		var e error
+		switch {
+		case c <= POSFIXNUMMAX || c >= NEGFIXNUM:
+		case c <= FIXMAPMAX:
+			pending += 2 * uint64(c&FIRSTBYTEMASK)
+		case c <= FIXARRAYMAX:
+			pending += uint64(c & FIRSTBYTEMASK)
+		case c <= FIXRAWMAX:
+			skip = uint64(c & FIXRAWMASK)
+		default:
+			switch c {
+			case NIL, FALSE, TRUE:
+			case UINT8, INT8:
+				skip = 1
+			case UINT16, INT16:
+				skip = 2
+			case UINT32, INT32, FLOAT32:
+				skip = 4
+			case UINT64, INT64, FLOAT64:
+				skip = 8
+			case FIXEXT1:
+				skip = 2
+			case FIXEXT2:
+				skip = 3
+			case FIXEXT4:
+				skip = 5
+			case FIXEXT8:
+				skip = 9
+			case FIXEXT16:
+				skip = 17
+			case BIN8, STR8:
+				skip, e = readLength(reader, 1)
+			case BIN16, STR16:
+				skip, e = readLength(reader, 2)
+			case BIN32, STR32:
+				skip, e = readLength(reader, 4)
+			case EXT8:
+				skip, e = readLength(reader, 1)
+				skip++
+			case EXT16:
+				skip, e = readLength(reader, 2)
+				skip++
+			case EXT32:
+				skip, e = readLength(reader, 4)
+				skip++
+			case ARRAY16, ARRAY32, MAP16, MAP32:
+				var size uint64
+				if c == ARRAY16 || c == MAP16 {
+					size, e = readLength(reader, 2)
+				} else {
+					size, e = readLength(reader, 4)
+				}
+				if c == MAP16 || c == MAP32 {
+					size *= 2
+				}
+				pending += size
+			default:
+				return fmt.Errorf("Invalid Type 0x%02x", c)
+			}
+		}
+		if e != nil {
+			return e
+		}
+
+		if skip > 0 {
+			if _, e := io.CopyN(ioutil.Discard, reader, int64(skip)); e != nil {
+				if e == io.EOF {
+					return io.ErrUnexpectedEOF
+				}
+				return e
 			}
 		}
 	}
 
-	return "", e
+	return nil
 }
 
-//UnpackBin16 is to unpack message
-func UnpackBin16(reader io.Reader) ([]byte, error) {
-	c, e := readByte(reader)
-	if e == nil && c == BIN16  {
-		size, _, e := readUint16(reader)
-		if e == nil {
-			value := make([]byte, size)
-			n, e := reader.Read(value)
-			if e == nil && uint16(n) == size {
-				return value, nil
+//Skip consumes exactly one complete value of any type from reader,
+//including all elements of nested arrays and maps
+func Skip(reader io.Reader) error {
+	return skipValue(reader, nil)
+}
+
+//PeekType reports the type family of the next value without consuming it
+func PeekType(reader io.ByteScanner) (Type, error) {
+	c, e := reader.ReadByte()
+	if e != nil {
+		return InvalidType, e
+	}
+	if e = reader.UnreadByte(); e != nil {
+		return InvalidType, e
+	}
+	return typeOf(c), nil
+}
+
+//UnmarshalAbiEx is to unserialize the message into an ordered FeildMap, using
+//the abi struct of method for field names and types
+func UnmarshalAbiEx(data []byte, Abi *ABI, contractName string, method string) (*FeildMap, error) {
+	if Abi == nil {
+		return nil, fmt.Errorf("UnmarshalAbiEx: abi is nil")
+	}
+
+	r := bytes.NewReader(data)
+	return decodeAbiEx(contractName, method, r, Abi, "")
+}
+
+//DecodeAbiEx is to decode message. Values are string, uint8 to uint64,
+//[]byte, *FeildMap for structs, []interface{} for arrays and nil for absent
+//optionals.
+func DecodeAbiEx(contractName string, method string, r io.Reader, abi ABI, subStructName string) (*FeildMap, error) {
+	return decodeAbiEx(contractName, method, r, &abi, subStructName)
+}
+
+//decodeAbiEx is DecodeAbiEx without copying the abi
+func decodeAbiEx(contractName string, method string, r io.Reader, abi *ABI, subStructName string) (*FeildMap, error) {
+	abiFieldsAttr, err := getAbiFieldsByAbiEx(contractName, method, abi, subStructName)
+	if err != nil {
+		return nil, fmt.Errorf("DecodeAbiEx: getAbiFieldsByAbi failed: %v", err)
+	}
+
+	abiFields := abiFieldsAttr.GetStringPair()
+
+	size, err := UnpackArraySize(r)
+	if err != nil {
+		return nil, err
+	}
+	if int(size) != len(abiFields) {
+		return nil, fmt.Errorf("DecodeAbiEx: fields number mismatch! abi: %d, data: %d", len(abiFields), size)
+	}
+
+	value := New()
+	for _, abiValTypeAttr := range abiFields {
+		abiValKey := abiValTypeAttr.Key
+
+		val, err := decodeAbiValueEx(contractName, method, r, abi, abiValTypeAttr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("DecodeAbiEx: %s: %v", abiValKey, err)
+		}
+
+		value.Set(abiValKey, val)
+	}
+
+	return value, nil
+}
+
+//unpackOptional reads the header of an optional value. It returns a nil
+//reader if the value is absent, or a reader that yields the value otherwise.
+func unpackOptional(r io.Reader) (io.Reader, error) {
+	c, err := readByte(r)
+	if err != nil {
+		return nil, err
+	}
+	if c == NIL {
+		return nil, nil
+	}
+	return io.MultiReader(bytes.NewReader([]byte{c}), r), nil
+}
+
+func decodeAbiValueEx(contractName string, method string, r io.Reader, abi *ABI, abiType string) (interface{}, error) {
+	abiType, err := resolveAbiType(abi, abiType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(abiType, "?"):
+		vr, err := unpackOptional(r)
+		if vr == nil || err != nil {
+			return nil, err
+		}
+		return decodeAbiValueEx(contractName, method, vr, abi, strings.TrimSuffix(abiType, "?"))
+	case strings.HasSuffix(abiType, "[]"):
+		size, err := UnpackArraySize(r)
+		if err != nil {
+			return nil, err
+		}
+		vals := make([]interface{}, size)
+		for i := range vals {
+			vals[i], err = decodeAbiValueEx(contractName, method, r, abi, strings.TrimSuffix(abiType, "[]"))
+			if err != nil {
+				return nil, err
 			}
 		}
+		return vals, nil
+	}
+
+	if variant := getAbiVariant(abi, abiType); variant != nil {
+		return decodeAbiVariant(contractName, method, r, abi, variant)
 	}
 
-	return []byte{}, e
+	switch abiType {
+	case "string":
+		return UnpackStr16(r)
+	case "uint8":
+		return UnpackUint8(r)
+	case "uint16":
+		return UnpackUint16(r)
+	case "uint32":
+		return UnpackUint32(r)
+	case "uint64":
+		return UnpackUint64(r)
+	case "bytes":
+		return UnpackBin16(r)
+	}
+	return decodeAbiEx(contractName, method, r, abi, abiType)
 }
 
+//decodeAbiVariant decodes [type_index, value] into a Variant
+func decodeAbiVariant(contractName string, method string, r io.Reader, abi *ABI, variant *ABIVariant) (Variant, error) {
+	size, err := UnpackArraySize(r)
+	if err != nil {
+		return Variant{}, err
+	}
+	if size != 2 {
+		return Variant{}, fmt.Errorf("variant %s: array size %d, want 2", variant.Name, size)
+	}
+
+	index, err := UnpackUint8(r)
+	if err != nil {
+		return Variant{}, err
+	}
+	if int(index) >= len(variant.Types) {
+		return Variant{}, fmt.Errorf("variant %s: type index %d out of range", variant.Name, index)
+	}
+
+	typ := variant.Types[index]
+	val, err := decodeAbiValueEx(contractName, method, r, abi, typ)
+	if err != nil {
+		return Variant{}, err
+	}
+	return Variant{Type: typ, Value: val}, nil
+}
+
+//UnmarshalAbi is to unserialize the message into the struct pointed to by v.
+//Struct fields are matched to the abi fields of method by their msgpack or
+//json tag, and both the Go type and the wire type are checked against the abi.
+func UnmarshalAbi(data []byte, v interface{}, Abi *ABI, contractName string, method string) error {
+	if Abi == nil {
+		return fmt.Errorf("UnmarshalAbi: abi is nil")
+	}
+
+	r := bytes.NewReader(data)
+	return decodeAbi(contractName, method, r, v, Abi, "")
+}
+
+//DecodeAbi is to decode message. Array types `T[]` are decoded into slices
+//and optional types `T?` into pointers.
+func DecodeAbi(contractName string, method string, r io.Reader, dst interface{}, abi ABI, subStructName string) error {
+	return decodeAbi(contractName, method, r, dst, &abi, subStructName)
+}
+
+//decodeAbi is DecodeAbi without copying the abi
+func decodeAbi(contractName string, method string, r io.Reader, dst interface{}, abi *ABI, subStructName string) error {
+	abiFieldsAttr, err := getAbiFieldsByAbiEx(contractName, method, abi, subStructName)
+	if err != nil {
+		return fmt.Errorf("DecodeAbi: getAbiFieldsByAbi failed: %v", err)
+	}
+
+	v := reflect.ValueOf(dst)
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("DecodeAbi: dst Not Settable %T", dst)
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeAbi: Unsupported Type: %T", dst)
+	}
+
+	abiFields := abiFieldsAttr.GetStringPair()
+
+	fieldIndex := map[string]int{}
+	for i := 0; i < v.NumField(); i++ {
+		fieldname := abiFieldName(v.Type().Field(i))
+		if _, ok := abiFieldsAttr.Get(fieldname); !ok {
+			return fmt.Errorf("DecodeAbi: %s is not in abi struct fields %v", fieldname, abiFieldsAttr.Keys())
+		}
+		fieldIndex[fieldname] = i
+	}
+
+	size, err := UnpackArraySize(r)
+	if err != nil {
+		return err
+	}
+	if int(size) != len(abiFields) {
+		return fmt.Errorf("DecodeAbi: fields number mismatch! abi: %d, data: %d", len(abiFields), size)
+	}
+
+	for _, abiValTypeAttr := range abiFields {
+		abiValKey := abiValTypeAttr.Key
+
+		i, ok := fieldIndex[abiValKey]
+		if !ok {
+			return fmt.Errorf("DecodeAbi: abi field %s not found in %v", abiValKey, v.Type())
+		}
+
+		err = decodeAbiValue(contractName, method, r, abi, abiValTypeAttr.Value, v.Field(i))
+		if err != nil {
+			return fmt.Errorf("DecodeAbi: %s: %v", abiValKey, err)
+		}
+	}
+
+	return nil
+}
+
+func decodeAbiValue(contractName string, method string, r io.Reader, abi *ABI, abiType string, feild reflect.Value) error {
+	abiType, err := resolveAbiType(abi, abiType)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasSuffix(abiType, "?"):
+		if feild.Kind() != reflect.Ptr {
+			return fmt.Errorf("abiValType %s mismatch to %v", abiType, feild.Type())
+		}
+		vr, err := unpackOptional(r)
+		if err != nil {
+			return err
+		}
+		if vr == nil {
+			feild.Set(reflect.Zero(feild.Type()))
+			return nil
+		}
+		val := reflect.New(feild.Type().Elem())
+		err = decodeAbiValue(contractName, method, vr, abi, strings.TrimSuffix(abiType, "?"), val.Elem())
+		if err != nil {
+			return err
+		}
+		feild.Set(val)
+		return nil
+	case strings.HasSuffix(abiType, "[]"):
+		if feild.Kind() != reflect.Slice {
+			return fmt.Errorf("abiValType %s mismatch to %v", abiType, feild.Type())
+		}
+		size, err := UnpackArraySize(r)
+		if err != nil {
+			return err
+		}
+		vals := reflect.MakeSlice(feild.Type(), int(size), int(size))
+		for i := 0; i < int(size); i++ {
+			err = decodeAbiValue(contractName, method, r, abi, strings.TrimSuffix(abiType, "[]"), vals.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		feild.Set(vals)
+		return nil
+	}
+
+	if variant := getAbiVariant(abi, abiType); variant != nil {
+		if feild.Kind() == reflect.Ptr && feild.Type().Elem() == variantType {
+			if feild.IsNil() {
+				feild.Set(reflect.New(variantType))
+			}
+			feild = feild.Elem()
+		}
+		if feild.Type() != variantType {
+			return fmt.Errorf("abiValType %s mismatch to %v", abiType, feild.Type())
+		}
+		val, err := decodeAbiVariant(contractName, method, r, abi, variant)
+		if err != nil {
+			return err
+		}
+		feild.Set(reflect.ValueOf(val))
+		return nil
+	}
+
+	if !abiTypeMatch(abiType, feild.Type()) {
+		return fmt.Errorf("abiValType %s mismatch to %v", abiType, feild.Type())
+	}
+
+	switch abiType {
+	case "string":
+		var val string
+		val, err = UnpackStr16(r)
+		feild.SetString(val)
+	case "uint8":
+		var val uint8
+		val, err = UnpackUint8(r)
+		feild.SetUint(uint64(val))
+	case "uint16":
+		var val uint16
+		val, err = UnpackUint16(r)
+		feild.SetUint(uint64(val))
+	case "uint32":
+		var val uint32
+		val, err = UnpackUint32(r)
+		feild.SetUint(uint64(val))
+	case "uint64":
+		var val uint64
+		val, err = UnpackUint64(r)
+		feild.SetUint(val)
+	case "bytes":
+		var val []byte
+		val, err = UnpackBin16(r)
+		feild.SetBytes(val)
+	default:
+		if feild.Kind() == reflect.Ptr {
+			if feild.IsNil() {
+				feild.Set(reflect.New(feild.Type().Elem()))
+			}
+			err = decodeAbi(contractName, method, r, feild.Interface(), abi, abiType)
+		} else {
+			err = decodeAbi(contractName, method, r, feild.Addr().Interface(), abi, abiType)
+		}
+	}
+
+	return err
+}
+
+//abiTypeMatch reports whether a Go field of type t can hold abi type abiType
+func abiTypeMatch(abiType string, t reflect.Type) bool {
+	switch abiType {
+	case "string":
+		return t.Kind() == reflect.String
+	case "uint8":
+		return t.Kind() == reflect.Uint8
+	case "uint16":
+		return t.Kind() == reflect.Uint16
+	case "uint32":
+		return t.Kind() == reflect.Uint32
+	case "uint64":
+		return t.Kind() == reflect.Uint64
+	case "bytes":
+		return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+	}
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
@@ -0,0 +1,259 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//AbiError is one problem found by ABI.Validate, Path is a JSON path into the abi
+type AbiError struct {
+	Path string
+	Msg  string
+}
+
+func (e *AbiError) Error() string {
+	return e.Path + ": " + e.Msg
+}
+
+//AbiErrors is the list of problems found by ABI.Validate
+type AbiErrors []*AbiError
+
+func (e AbiErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+//isAbiPrimitive reports whether typ is a builtin abi type
+func isAbiPrimitive(typ string) bool {
+	switch typ {
+	case "string", "uint8", "uint16", "uint32", "uint64", "bytes":
+		return true
+	}
+	return false
+}
+
+//checkAbiType checks that typ is a known primitive, alias, struct or
+//variant, possibly wrapped as an array or optional
+func checkAbiType(abi *ABI, typ string) error {
+	if typ == "" {
+		return fmt.Errorf("empty type")
+	}
+
+	resolved, err := resolveAbiType(abi, typ)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasSuffix(resolved, "[]"):
+		return checkAbiType(abi, strings.TrimSuffix(resolved, "[]"))
+	case strings.HasSuffix(resolved, "?"):
+		return checkAbiType(abi, strings.TrimSuffix(resolved, "?"))
+	}
+
+	if isAbiPrimitive(resolved) || getAbiStruct(abi, resolved) != nil || getAbiVariant(abi, resolved) != nil {
+		return nil
+	}
+	if resolved != typ {
+		return fmt.Errorf("unknown type %s (alias of %s)", resolved, typ)
+	}
+	return fmt.Errorf("unknown type %s", typ)
+}
+
+//Validate checks that every action type is a struct, every field type is a
+//known primitive, alias, struct or variant, names are neither empty nor
+//duplicated, and base chains and aliases have no cycles. All problems found
+//are returned as AbiErrors. Duplicate field names are lost once the abi JSON
+//is parsed, ParseAbi with ValidateAbi reports them.
+func (abi *ABI) Validate() error {
+	var errs AbiErrors
+	add := func(path string, format string, args ...interface{}) {
+		errs = append(errs, &AbiError{Path: path, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	names := map[string]string{}
+	declare := func(path string, kind string, name string) {
+		if name == "" {
+			add(path, "empty %s name", kind)
+			return
+		}
+		if isAbiPrimitive(name) {
+			add(path, "%s name %s shadows a builtin type", kind, name)
+			return
+		}
+		if prev, ok := names[name]; ok {
+			add(path, "duplicate name %s, first declared at %s", name, prev)
+			return
+		}
+		names[name] = path
+	}
+
+	for i, t := range abi.Types {
+		declare(fmt.Sprintf("$.types[%d].new_type_name", i), "type", t.NewTypeName)
+	}
+	for i, st := range abi.Structs {
+		declare(fmt.Sprintf("$.structs[%d].name", i), "struct", st.Name)
+	}
+	for i, v := range abi.Variants {
+		declare(fmt.Sprintf("$.variants[%d].name", i), "variant", v.Name)
+	}
+
+	for i, t := range abi.Types {
+		if err := checkAbiType(abi, t.Type); err != nil {
+			add(fmt.Sprintf("$.types[%d].type", i), "%v", err)
+		}
+	}
+
+	for i, st := range abi.Structs {
+		path := fmt.Sprintf("$.structs[%d]", i)
+		if st.Base != "" {
+			if _, err := abiStructFields(abi, st.Name); err != nil {
+				add(path+".base", "%v", err)
+			}
+		}
+		if st.Fields == nil {
+			continue
+		}
+		for _, k := range st.Fields.Keys() {
+			if k == "" {
+				add(path+".fields", "empty field name")
+				continue
+			}
+			typ, _ := st.Fields.GetStringVal(k)
+			if err := checkAbiType(abi, typ); err != nil {
+				add(path+".fields."+k, "%v", err)
+			}
+		}
+	}
+
+	for i, v := range abi.Variants {
+		path := fmt.Sprintf("$.variants[%d].types", i)
+		if len(v.Types) == 0 {
+			add(path, "variant %s has no types", v.Name)
+		}
+		if len(v.Types) > REGULAR_UINT8_MAX {
+			add(path, "variant %s has more than %d types", v.Name, REGULAR_UINT8_MAX)
+		}
+		for j, typ := range v.Types {
+			if err := checkAbiType(abi, typ); err != nil {
+				add(fmt.Sprintf("%s[%d]", path, j), "%v", err)
+			}
+		}
+	}
+
+	actions := map[string]string{}
+	for i, a := range abi.Actions {
+		path := fmt.Sprintf("$.actions[%d]", i)
+		if a.ActionName == "" {
+			add(path+".action_name", "empty action name")
+		} else if prev, ok := actions[a.ActionName]; ok {
+			add(path+".action_name", "duplicate action %s, first declared at %s", a.ActionName, prev)
+		} else {
+			actions[a.ActionName] = path
+		}
+
+		typ, err := resolveAbiType(abi, a.Type)
+		if err != nil {
+			add(path+".type", "%v", err)
+		} else if getAbiStruct(abi, typ) == nil {
+			add(path+".type", "undefined struct %s", a.Type)
+		}
+	}
+
+	tables := map[string]string{}
+	for i, tbl := range abi.Tables {
+		path := fmt.Sprintf("$.tables[%d]", i)
+		if tbl.Name == "" {
+			add(path+".table_name", "empty table name")
+		} else if prev, ok := tables[tbl.Name]; ok {
+			add(path+".table_name", "duplicate table %s, first declared at %s", tbl.Name, prev)
+		} else {
+			tables[tbl.Name] = path
+		}
+
+		if len(tbl.KeyNames) != len(tbl.KeyTypes) {
+			add(path+".key_types", "%d key_names but %d key_types", len(tbl.KeyNames), len(tbl.KeyTypes))
+		}
+		for j, typ := range tbl.KeyTypes {
+			if err := checkAbiType(abi, typ); err != nil {
+				add(fmt.Sprintf("%s.key_types[%d]", path, j), "%v", err)
+			}
+		}
+
+		typ, err := resolveAbiType(abi, tbl.Type)
+		if err != nil {
+			add(path+".type", "%v", err)
+			continue
+		}
+		if getAbiStruct(abi, typ) == nil {
+			add(path+".type", "undefined struct %s", tbl.Type)
+			continue
+		}
+		fields, err := abiStructFields(abi, typ)
+		if err != nil {
+			continue
+		}
+		for j, name := range tbl.KeyNames {
+			if _, ok := fields.Get(name); !ok {
+				add(fmt.Sprintf("%s.key_names[%d]", path, j), "key %s is not a field of %s", name, tbl.Type)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//duplicateJSONKeys reports every object key of raw that repeats an earlier
+//key of the same object, which encoding/json silently overwrites
+func duplicateJSONKeys(raw []byte) (AbiErrors, error) {
+	var errs AbiErrors
+	d := json.NewDecoder(bytes.NewReader(raw))
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			seen := map[string]bool{}
+			for d.More() {
+				key, err := d.Token()
+				if err != nil {
+					return err
+				}
+				k := key.(string)
+				if seen[k] {
+					errs = append(errs, &AbiError{Path: path + "." + k, Msg: "duplicate key " + k})
+				}
+				seen[k] = true
+				if err = walk(path + "." + k); err != nil {
+					return err
+				}
+			}
+			_, err = d.Token()
+			return err
+		case json.Delim('['):
+			for i := 0; d.More(); i++ {
+				if err = walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = d.Token()
+			return err
+		}
+		return nil
+	}
+	if err := walk("$"); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}